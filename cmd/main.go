@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/DenisBarabanshchikov/subscription/config"
 	"github.com/DenisBarabanshchikov/subscription/di"
 	_ "github.com/DenisBarabanshchikov/subscription/docs"
@@ -14,6 +15,11 @@ import (
 // @title       Subscription Service API Documentation
 // @version     1.0.0
 // @description This is the API documentation for the subscription service.
+
+// @securityDefinitions.apikey AdminToken
+// @in                         header
+// @name                       Authorization
+// @description                Admin token as "Bearer <token>"
 func main() {
 	router := gin.Default()
 
@@ -26,19 +32,70 @@ func main() {
 		log.Fatalf("failed to initialize handlers: %v", err)
 	}
 
+	// Retry failed webhook events in the background
+	retryWorker, err := di.InitializeRetryWorker()
+	if err != nil {
+		log.Fatalf("failed to initialize retry worker: %v", err)
+	}
+	go retryWorker.Run(context.Background())
+
+	// Keep the plan catalog in line with the products in Stripe
+	planSyncWorker, err := di.InitializePlanSyncWorker()
+	if err != nil {
+		log.Fatalf("failed to initialize plan sync worker: %v", err)
+	}
+	go planSyncWorker.Run(context.Background())
+
 	// Routes
 	api := router.Group("/api/v1")
 	{
 		api.POST("/customers", h.SubscriptionHandler.CreateCustomer)
 
+		// Plan catalog
+		api.GET("/plans", h.PlanHandler.GetPlans)
+		api.GET("/plans/:planId", h.PlanHandler.GetPlan)
+
 		// 2) Create a new subscription for a given customer
 		api.POST("/customers/:customerId/subscriptions", h.SubscriptionHandler.SubscribeCustomer)
+		api.POST("/customers/:customerId/checkout-sessions", h.SubscriptionHandler.CreateCheckoutSession)
+		api.POST("/customers/:customerId/portal-sessions", h.SubscriptionHandler.CreatePortalSession)
+		api.POST("/customers/:customerId/setup-intents", h.SubscriptionHandler.CreateSetupIntent)
+		api.GET("/customers/:customerId/payment-methods", h.SubscriptionHandler.GetPaymentMethods)
+		api.POST("/customers/:customerId/payment-methods/:paymentMethodId/default", h.SubscriptionHandler.SetDefaultPaymentMethod)
+		api.DELETE("/customers/:customerId/payment-methods/:paymentMethodId", h.SubscriptionHandler.DetachPaymentMethod)
 
 		// 3) Retrieve a subscription’s status (or details) for a given customer
 		api.GET("/customers/:customerId/subscriptions/:subscriptionId", h.SubscriptionHandler.GetSubscriptionStatus)
+		api.PATCH("/customers/:customerId/subscriptions/:subscriptionId", h.SubscriptionHandler.ChangePlan)
+		api.DELETE("/customers/:customerId/subscriptions/:subscriptionId", h.SubscriptionHandler.CancelSubscription)
+		api.PUT("/customers/:customerId/subscriptions/:subscriptionId/quantity", h.SubscriptionHandler.ChangeQuantity)
+		api.GET("/customers/:customerId/subscriptions/:subscriptionId/add-ons", h.SubscriptionHandler.GetAddOns)
+		api.POST("/customers/:customerId/subscriptions/:subscriptionId/add-ons", h.SubscriptionHandler.AddAddOn)
+		api.DELETE("/customers/:customerId/subscriptions/:subscriptionId/add-ons/:addOn", h.SubscriptionHandler.RemoveAddOn)
+		api.POST("/customers/:customerId/subscriptions/:subscriptionId/pause", h.SubscriptionHandler.PauseSubscription)
+		api.POST("/customers/:customerId/subscriptions/:subscriptionId/resume", h.SubscriptionHandler.ResumeSubscription)
+
+		// Status history of a subscription
+		api.GET("/customers/:customerId/subscriptions/:subscriptionId/history", h.SubscriptionHandler.GetSubscriptionHistory)
+		api.GET("/customers/:customerId/subscriptions/:subscriptionId/plan-preview", h.SubscriptionHandler.PreviewPlanChange)
 
 		// 4) Handle Stripe webhook events
-		api.POST("/stripe/webhook", h.SubscriptionHandler.HandleStripeWebhook)
+		api.POST("/stripe/webhook", h.WebhookHandler.HandleStripeWebhook)
+	}
+
+	admin := api.Group("/admin", h.AdminMiddleware.Authorize)
+	{
+		// Webhook event log and replay
+		admin.GET("/events", h.EventHandler.ListEvents)
+		admin.POST("/events/replay", h.EventHandler.ReplayEvents)
+		admin.GET("/events/:eventId", h.EventHandler.GetEvent)
+		admin.POST("/events/:eventId/replay", h.EventHandler.ReplayEvent)
+
+		// Plan catalog management
+		admin.POST("/plans", h.PlanHandler.CreatePlan)
+		admin.POST("/plans/sync", h.PlanHandler.SyncPlans)
+		admin.PUT("/plans/:planId", h.PlanHandler.UpdatePlan)
+		admin.DELETE("/plans/:planId", h.PlanHandler.ArchivePlan)
 	}
 
 	// Run server
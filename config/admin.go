@@ -0,0 +1,12 @@
+package config
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http"
+	"github.com/DenisBarabanshchikov/subscription/pkg/env"
+)
+
+func ProvideAdminConfig() http.AdminConfig {
+	return http.AdminConfig{
+		Token: env.RequiredString("ADMIN_API_TOKEN"),
+	}
+}
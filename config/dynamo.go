@@ -1,6 +1,8 @@
 package config
 
 import (
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/event"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/plan"
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/subscription"
 	"github.com/DenisBarabanshchikov/subscription/pkg/dynamo_client"
 	"github.com/DenisBarabanshchikov/subscription/pkg/env"
@@ -28,3 +30,19 @@ func ProvideSubscriptionDynamoConfig() subscription.DynamoConfig {
 		QueryTimeout: env.RequiredDuration("DYNAMO_SUBSCRIPTION_TIMEOUT"),
 	}
 }
+
+func ProvideEventDynamoConfig() event.DynamoConfig {
+	return event.DynamoConfig{
+		Client:       GetDynamoClient(),
+		Table:        env.RequiredString("DYNAMO_SUBSCRIPTION_TABLE"),
+		QueryTimeout: env.RequiredDuration("DYNAMO_SUBSCRIPTION_TIMEOUT"),
+	}
+}
+
+func ProvidePlanDynamoConfig() plan.DynamoConfig {
+	return plan.DynamoConfig{
+		Client:       GetDynamoClient(),
+		Table:        env.RequiredString("DYNAMO_SUBSCRIPTION_TABLE"),
+		QueryTimeout: env.RequiredDuration("DYNAMO_SUBSCRIPTION_TIMEOUT"),
+	}
+}
@@ -0,0 +1,12 @@
+package config
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/worker"
+	"github.com/DenisBarabanshchikov/subscription/pkg/env"
+)
+
+func ProvidePlanSyncWorkerConfig() worker.PlanSyncConfig {
+	return worker.PlanSyncConfig{
+		Interval: env.RequiredDuration("PLAN_SYNC_INTERVAL"),
+	}
+}
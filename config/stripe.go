@@ -1,6 +1,7 @@
 package config
 
 import (
+	paymentProvider "github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
 	"github.com/DenisBarabanshchikov/subscription/pkg/env"
 	"github.com/stripe/stripe-go/v74/client"
 )
@@ -13,3 +14,32 @@ func ProvideStripeClient() *client.API {
 
 	return sc
 }
+
+func ProvideStripeAddOnConfig() paymentProvider.AddOnConfig {
+	return paymentProvider.AddOnConfig{
+		Prices: map[string]map[string]string{
+			"ExtraStorage":    env.OptionalMap("STRIPE_ADDON_EXTRA_STORAGE_PRICES"),
+			"PrioritySupport": env.OptionalMap("STRIPE_ADDON_PRIORITY_SUPPORT_PRICES"),
+		},
+	}
+}
+
+func ProvideStripePlanConfig() paymentProvider.PlanConfig {
+	return paymentProvider.PlanConfig{
+		Environment: env.RequiredString("STRIPE_ENVIRONMENT"),
+	}
+}
+
+func ProvideStripePortalConfig() paymentProvider.PortalConfig {
+	return paymentProvider.PortalConfig{
+		ReturnUrl:       env.OptionalString("STRIPE_PORTAL_RETURN_URL"),
+		ConfigurationId: env.OptionalString("STRIPE_PORTAL_CONFIGURATION"),
+	}
+}
+
+func ProvideStripeWebhookConfig() paymentProvider.WebhookConfig {
+	return paymentProvider.WebhookConfig{
+		Secret:    env.RequiredString("STRIPE_WEBHOOK_SECRET"),
+		Tolerance: env.OptionalDuration("STRIPE_WEBHOOK_TOLERANCE"),
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/worker"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"github.com/DenisBarabanshchikov/subscription/pkg/env"
+)
+
+func ProvideWebhookRetryConfig() service.RetryConfig {
+	return service.RetryConfig{
+		MaxAttempts: env.RequiredInt("WEBHOOK_RETRY_MAX_ATTEMPTS"),
+		BaseDelay:   env.RequiredDuration("WEBHOOK_RETRY_BASE_DELAY"),
+		MaxDelay:    env.RequiredDuration("WEBHOOK_RETRY_MAX_DELAY"),
+		BatchSize:   env.RequiredInt("WEBHOOK_RETRY_BATCH_SIZE"),
+	}
+}
+
+func ProvideRetryWorkerConfig() worker.RetryConfig {
+	return worker.RetryConfig{
+		Interval: env.RequiredDuration("WEBHOOK_RETRY_INTERVAL"),
+	}
+}
@@ -5,9 +5,12 @@ package di
 
 import (
 	"github.com/DenisBarabanshchikov/subscription/config"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/event"
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/plan"
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/subscription"
 	"github.com/DenisBarabanshchikov/subscription/internal/handler/http"
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/worker"
 	"github.com/DenisBarabanshchikov/subscription/internal/port"
 	"github.com/DenisBarabanshchikov/subscription/internal/service"
 	"github.com/google/wire"
@@ -16,6 +19,16 @@ import (
 
 var configs = wire.NewSet(
 	config.ProvideSubscriptionDynamoConfig,
+	config.ProvideEventDynamoConfig,
+	config.ProvidePlanDynamoConfig,
+	config.ProvideStripeWebhookConfig,
+	config.ProvideStripePortalConfig,
+	config.ProvideStripePlanConfig,
+	config.ProvideStripeAddOnConfig,
+	config.ProvideAdminConfig,
+	config.ProvideWebhookRetryConfig,
+	config.ProvideRetryWorkerConfig,
+	config.ProvidePlanSyncWorkerConfig,
 )
 
 var clients = wire.NewSet(
@@ -24,6 +37,8 @@ var clients = wire.NewSet(
 
 var repositories = wire.NewSet(
 	subscriptionRepository,
+	eventRepository,
+	planRepository,
 )
 
 var api = wire.NewSet(
@@ -32,6 +47,8 @@ var api = wire.NewSet(
 
 var ports = wire.NewSet(
 	subscriptionPort,
+	eventPort,
+	planPort,
 	paymentProviderPort,
 )
 
@@ -42,7 +59,21 @@ func subscriptionRepository(config subscription.DynamoConfig) subscription.Repos
 	return nil
 }
 
-func stripeApi(client *client.API) stripe.Api {
+func eventRepository(config event.DynamoConfig) event.Repository {
+	wire.Build(
+		event.NewDynamoRepository,
+	)
+	return nil
+}
+
+func planRepository(config plan.DynamoConfig) plan.Repository {
+	wire.Build(
+		plan.NewDynamoRepository,
+	)
+	return nil
+}
+
+func stripeApi(client *client.API, webhookConfig stripe.WebhookConfig, portalConfig stripe.PortalConfig) stripe.Api {
 	wire.Build(
 		stripe.NewApi,
 	)
@@ -56,7 +87,21 @@ func subscriptionPort(repository subscription.Repository) port.Subscription {
 	return nil
 }
 
-func paymentProviderPort(api stripe.Api) port.PaymentProvider {
+func eventPort(repository event.Repository) port.Event {
+	wire.Build(
+		event.NewAdapter,
+	)
+	return nil
+}
+
+func planPort(repository plan.Repository) port.Plan {
+	wire.Build(
+		plan.NewAdapter,
+	)
+	return nil
+}
+
+func paymentProviderPort(api stripe.Api, addOns stripe.AddOnConfig, plans port.Plan, planConfig stripe.PlanConfig) port.PaymentProvider {
 	wire.Build(
 		stripe.NewAdapter,
 	)
@@ -71,8 +116,40 @@ func InitializeHandlers() (*http.Handlers, error) {
 		repositories,
 		ports,
 		service.NewSubscriptionService,
+		service.NewWebhookService,
+		service.NewPlanService,
 		http.NewSubscriptionHandler,
+		http.NewWebhookHandler,
+		http.NewEventHandler,
+		http.NewPlanHandler,
+		http.NewAdminMiddleware,
 		http.NewHandlers,
 	)
 	return &http.Handlers{}, nil
 }
+
+func InitializeRetryWorker() (*worker.RetryWorker, error) {
+	wire.Build(
+		configs,
+		clients,
+		api,
+		repositories,
+		ports,
+		service.NewWebhookService,
+		worker.NewRetryWorker,
+	)
+	return &worker.RetryWorker{}, nil
+}
+
+func InitializePlanSyncWorker() (*worker.PlanSyncWorker, error) {
+	wire.Build(
+		configs,
+		clients,
+		api,
+		repositories,
+		ports,
+		service.NewPlanService,
+		worker.NewPlanSyncWorker,
+	)
+	return &worker.PlanSyncWorker{}, nil
+}
@@ -8,9 +8,12 @@ package di
 
 import (
 	"github.com/DenisBarabanshchikov/subscription/config"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/event"
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/plan"
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/subscription"
 	"github.com/DenisBarabanshchikov/subscription/internal/handler/http"
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/worker"
 	"github.com/DenisBarabanshchikov/subscription/internal/port"
 	"github.com/DenisBarabanshchikov/subscription/internal/service"
 	"github.com/google/wire"
@@ -24,8 +27,18 @@ func subscriptionRepository(config subscription.DynamoConfig) subscription.Repos
 	return repository
 }
 
-func stripeApi(client2 *client.API) stripe.Api {
-	api2 := stripe.NewApi(client2)
+func eventRepository(config event.DynamoConfig) event.Repository {
+	repository := event.NewDynamoRepository(config)
+	return repository
+}
+
+func planRepository(config plan.DynamoConfig) plan.Repository {
+	repository := plan.NewDynamoRepository(config)
+	return repository
+}
+
+func stripeApi(client2 *client.API, webhookConfig stripe.WebhookConfig, portalConfig stripe.PortalConfig) stripe.Api {
+	api2 := stripe.NewApi(client2, webhookConfig, portalConfig)
 	return api2
 }
 
@@ -34,8 +47,18 @@ func subscriptionPort(repository subscription.Repository) port.Subscription {
 	return portSubscription
 }
 
-func paymentProviderPort(api2 stripe.Api) port.PaymentProvider {
-	paymentProvider := stripe.NewAdapter(api2)
+func eventPort(repository event.Repository) port.Event {
+	portEvent := event.NewAdapter(repository)
+	return portEvent
+}
+
+func planPort(repository plan.Repository) port.Plan {
+	portPlan := plan.NewAdapter(repository)
+	return portPlan
+}
+
+func paymentProviderPort(api2 stripe.Api, addOns stripe.AddOnConfig, plans port.Plan, planConfig stripe.PlanConfig) port.PaymentProvider {
+	paymentProvider := stripe.NewAdapter(api2, addOns, plans, planConfig)
 	return paymentProvider
 }
 
@@ -44,22 +67,83 @@ func InitializeHandlers() (*http.Handlers, error) {
 	repository := subscriptionRepository(dynamoConfig)
 	portSubscription := subscriptionPort(repository)
 	clientAPI := config.ProvideStripeClient()
-	api2 := stripeApi(clientAPI)
-	paymentProvider := paymentProviderPort(api2)
-	subscriptionService := service.NewSubscriptionService(portSubscription, paymentProvider)
+	webhookConfig := config.ProvideStripeWebhookConfig()
+	portalConfig := config.ProvideStripePortalConfig()
+	api2 := stripeApi(clientAPI, webhookConfig, portalConfig)
+	addOnConfig := config.ProvideStripeAddOnConfig()
+	planDynamoConfig := config.ProvidePlanDynamoConfig()
+	planRepository2 := planRepository(planDynamoConfig)
+	portPlan := planPort(planRepository2)
+	planConfig := config.ProvideStripePlanConfig()
+	paymentProvider := paymentProviderPort(api2, addOnConfig, portPlan, planConfig)
+	subscriptionService := service.NewSubscriptionService(portSubscription, paymentProvider, portPlan)
 	subscriptionHandler := http.NewSubscriptionHandler(subscriptionService)
-	handlers := http.NewHandlers(subscriptionHandler)
+	eventDynamoConfig := config.ProvideEventDynamoConfig()
+	eventRepository2 := eventRepository(eventDynamoConfig)
+	portEvent := eventPort(eventRepository2)
+	retryConfig := config.ProvideWebhookRetryConfig()
+	webhookService := service.NewWebhookService(portSubscription, portEvent, paymentProvider, retryConfig)
+	webhookHandler := http.NewWebhookHandler(webhookService)
+	eventHandler := http.NewEventHandler(webhookService)
+	adminConfig := config.ProvideAdminConfig()
+	adminMiddleware := http.NewAdminMiddleware(adminConfig)
+	planService := service.NewPlanService(portPlan, paymentProvider)
+	planHandler := http.NewPlanHandler(planService)
+	handlers := http.NewHandlers(subscriptionHandler, webhookHandler, eventHandler, planHandler, adminMiddleware)
 	return handlers, nil
 }
 
+func InitializeRetryWorker() (*worker.RetryWorker, error) {
+	workerRetryConfig := config.ProvideRetryWorkerConfig()
+	dynamoConfig := config.ProvideSubscriptionDynamoConfig()
+	repository := subscriptionRepository(dynamoConfig)
+	portSubscription := subscriptionPort(repository)
+	eventDynamoConfig := config.ProvideEventDynamoConfig()
+	eventRepository2 := eventRepository(eventDynamoConfig)
+	portEvent := eventPort(eventRepository2)
+	clientAPI := config.ProvideStripeClient()
+	webhookConfig := config.ProvideStripeWebhookConfig()
+	portalConfig := config.ProvideStripePortalConfig()
+	api2 := stripeApi(clientAPI, webhookConfig, portalConfig)
+	addOnConfig := config.ProvideStripeAddOnConfig()
+	planDynamoConfig := config.ProvidePlanDynamoConfig()
+	planRepository2 := planRepository(planDynamoConfig)
+	portPlan := planPort(planRepository2)
+	planConfig := config.ProvideStripePlanConfig()
+	paymentProvider := paymentProviderPort(api2, addOnConfig, portPlan, planConfig)
+	retryConfig := config.ProvideWebhookRetryConfig()
+	webhookService := service.NewWebhookService(portSubscription, portEvent, paymentProvider, retryConfig)
+	retryWorker := worker.NewRetryWorker(workerRetryConfig, webhookService)
+	return retryWorker, nil
+}
+
+func InitializePlanSyncWorker() (*worker.PlanSyncWorker, error) {
+	planSyncConfig := config.ProvidePlanSyncWorkerConfig()
+	planDynamoConfig := config.ProvidePlanDynamoConfig()
+	repository := planRepository(planDynamoConfig)
+	portPlan := planPort(repository)
+	clientAPI := config.ProvideStripeClient()
+	webhookConfig := config.ProvideStripeWebhookConfig()
+	portalConfig := config.ProvideStripePortalConfig()
+	api2 := stripeApi(clientAPI, webhookConfig, portalConfig)
+	addOnConfig := config.ProvideStripeAddOnConfig()
+	planConfig := config.ProvideStripePlanConfig()
+	paymentProvider := paymentProviderPort(api2, addOnConfig, portPlan, planConfig)
+	planService := service.NewPlanService(portPlan, paymentProvider)
+	planSyncWorker := worker.NewPlanSyncWorker(planSyncConfig, planService)
+	return planSyncWorker, nil
+}
+
 // wire.go:
 
-var configs = wire.NewSet(config.ProvideSubscriptionDynamoConfig)
+var configs = wire.NewSet(config.ProvideSubscriptionDynamoConfig, config.ProvideEventDynamoConfig, config.ProvidePlanDynamoConfig, config.ProvideStripeWebhookConfig, config.ProvideStripePortalConfig, config.ProvideStripePlanConfig, config.ProvideStripeAddOnConfig, config.ProvideAdminConfig, config.ProvideWebhookRetryConfig, config.ProvideRetryWorkerConfig, config.ProvidePlanSyncWorkerConfig)
 
 var clients = wire.NewSet(config.ProvideStripeClient)
 
 var repositories = wire.NewSet(
 	subscriptionRepository,
+	eventRepository,
+	planRepository,
 )
 
 var api = wire.NewSet(
@@ -68,5 +152,7 @@ var api = wire.NewSet(
 
 var ports = wire.NewSet(
 	subscriptionPort,
+	eventPort,
+	planPort,
 	paymentProviderPort,
 )
@@ -0,0 +1,54 @@
+package event
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/port"
+	"time"
+)
+
+type adapter struct {
+	repository Repository
+}
+
+func NewAdapter(repository Repository) port.Event {
+	return &adapter{
+		repository: repository,
+	}
+}
+
+func (a *adapter) CreateEvent(ctx context.Context, event model.Event) error {
+	return a.repository.CreateEvent(ctx, mapToEventEntity(event))
+}
+
+func (a *adapter) GetEvent(ctx context.Context, eventId string) (*model.Event, error) {
+	event, err := a.repository.GetEvent(ctx, eventId)
+	if err != nil {
+		return nil, err
+	}
+	return mapToEventModelPtr(event), nil
+}
+
+func (a *adapter) UpdateEvent(ctx context.Context, event model.Event) error {
+	return a.repository.UpdateEvent(ctx, mapToEventEntity(event))
+}
+
+func (a *adapter) GetEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error) {
+	events, err := a.repository.GetEvents(ctx, mapToFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	return mapToEventsModel(events), nil
+}
+
+func (a *adapter) GetDueEvents(ctx context.Context, before time.Time, limit int) ([]model.Event, error) {
+	events, err := a.repository.GetDueEvents(ctx, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return mapToEventsModel(events), nil
+}
+
+func (a *adapter) ClaimEvent(ctx context.Context, event model.Event, until time.Time) (bool, error) {
+	return a.repository.ClaimEvent(ctx, mapToEventEntity(event), until)
+}
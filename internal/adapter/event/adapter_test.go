@@ -0,0 +1,244 @@
+//go:build unit
+
+package event_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/event"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+// mockRepository is a mock of the event.Repository interface
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) CreateEvent(ctx context.Context, entity event.Event) error {
+	args := m.Called(ctx, entity)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetEvent(ctx context.Context, eventId string) (*event.Event, error) {
+	args := m.Called(ctx, eventId)
+	if ee, ok := args.Get(0).(*event.Event); ok {
+		return ee, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) UpdateEvent(ctx context.Context, entity event.Event) error {
+	args := m.Called(ctx, entity)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetEvents(ctx context.Context, filter event.Filter) ([]event.Event, error) {
+	args := m.Called(ctx, filter)
+	if ee, ok := args.Get(0).([]event.Event); ok {
+		return ee, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) GetDueEvents(ctx context.Context, before time.Time, limit int) ([]event.Event, error) {
+	args := m.Called(ctx, before, limit)
+	if ee, ok := args.Get(0).([]event.Event); ok {
+		return ee, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) ClaimEvent(ctx context.Context, entity event.Event, until time.Time) (bool, error) {
+	args := m.Called(ctx, entity, until)
+	return args.Bool(0), args.Error(1)
+}
+
+// TestCreateEvent checks that the adapter calls repo.CreateEvent with the ledger fields
+func TestCreateEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	createdAt := time.Unix(1700000000, 0).UTC()
+	evt := model.Event{
+		EventId:   "evt_123",
+		Type:      "invoice.paid",
+		CreatedAt: createdAt,
+		Status:    model.EventStatusProcessing,
+		Attempts:  1,
+	}
+
+	mockRepo.
+		On("CreateEvent", ctx, mock.MatchedBy(func(e event.Event) bool {
+			return e.EventId == "evt_123" &&
+				e.Type == "invoice.paid" &&
+				e.Status == "processing" &&
+				e.Attempts == 1 &&
+				e.CreatedAt.Equal(createdAt)
+		})).
+		Return(nil).
+		Once()
+
+	err := adapter.CreateEvent(ctx, evt)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestCreateEvent_AlreadyExists checks that the duplicate error reaches the caller unchanged
+func TestCreateEvent_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	expectedErr := model.NewEventAlreadyExistsErr("evt_123")
+	mockRepo.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(expectedErr).
+		Once()
+
+	err := adapter.CreateEvent(ctx, model.Event{EventId: "evt_123"})
+	assert.Equal(t, expectedErr, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	repoEntity := event.Event{
+		EventId:  "evt_123",
+		Type:     "invoice.paid",
+		Status:   "failed",
+		Error:    "subscription 'sub_123' not found",
+		Attempts: 2,
+	}
+	mockRepo.
+		On("GetEvent", ctx, "evt_123").
+		Return(&repoEntity, nil).
+		Once()
+
+	evt, err := adapter.GetEvent(ctx, "evt_123")
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventStatusFailed, evt.Status)
+	assert.Equal(t, "subscription 'sub_123' not found", evt.Error)
+	assert.Equal(t, 2, evt.Attempts)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetEvent_Error(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	repoErr := errors.New("dynamo failure")
+	mockRepo.
+		On("GetEvent", ctx, "evt_123").
+		Return((*event.Event)(nil), repoErr).
+		Once()
+
+	evt, err := adapter.GetEvent(ctx, "evt_123")
+	assert.Nil(t, evt)
+	assert.Equal(t, repoErr, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e event.Event) bool {
+			return e.EventId == "evt_123" &&
+				e.Status == "processed" &&
+				e.Attempts == 2 &&
+				!e.UpdatedAt.IsZero()
+		})).
+		Return(nil).
+		Once()
+
+	err := adapter.UpdateEvent(ctx, model.Event{EventId: "evt_123", Status: model.EventStatusProcessed, Attempts: 2})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetEvents(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	from := time.Unix(1700000000, 0).UTC()
+	to := from.Add(time.Hour)
+	mockRepo.
+		On("GetEvents", ctx, event.Filter{Type: "invoice.paid", Status: "failed", From: from, To: to, Limit: 10}).
+		Return([]event.Event{
+			{EventId: "evt_1", Type: "invoice.paid", Status: "failed", Payload: []byte(`{"id":"evt_1"}`)},
+		}, nil).
+		Once()
+
+	events, err := adapter.GetEvents(ctx, model.EventFilter{
+		Type:   "invoice.paid",
+		Status: model.EventStatusFailed,
+		From:   from,
+		To:     to,
+		Limit:  10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "evt_1", events[0].EventId)
+	assert.Equal(t, []byte(`{"id":"evt_1"}`), events[0].Payload)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetDueEvents(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	before := time.Unix(1700000000, 0).UTC()
+	nextAttemptAt := before.Add(-time.Minute)
+	mockRepo.
+		On("GetDueEvents", ctx, before, 10).
+		Return([]event.Event{
+			{EventId: "evt_1", Status: "failed", Attempts: 2, NextAttemptAt: &nextAttemptAt},
+		}, nil).
+		Once()
+
+	events, err := adapter.GetDueEvents(ctx, before, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, model.EventStatusFailed, events[0].Status)
+	assert.Equal(t, &nextAttemptAt, events[0].NextAttemptAt)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestClaimEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := event.NewAdapter(mockRepo)
+
+	nextAttemptAt := time.Unix(1700000000, 0).UTC()
+	until := nextAttemptAt.Add(5 * time.Minute)
+	mockRepo.
+		On("ClaimEvent", ctx, mock.MatchedBy(func(e event.Event) bool {
+			return e.EventId == "evt_123" && e.NextAttemptAt.Equal(nextAttemptAt)
+		}), until).
+		Return(false, nil).
+		Once()
+
+	claimed, err := adapter.ClaimEvent(ctx, model.Event{EventId: "evt_123", NextAttemptAt: &nextAttemptAt}, until)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,15 @@
+package event
+
+import "time"
+
+type Event struct {
+	EventId       string     `dynamodbav:"EventId"`
+	Type          string     `dynamodbav:"Type"`
+	Status        string     `dynamodbav:"Status"`
+	Error         string     `dynamodbav:"Error"`
+	Attempts      int        `dynamodbav:"Attempts"`
+	NextAttemptAt *time.Time `dynamodbav:"NextAttemptAt,omitempty,unixtime"`
+	Payload       []byte     `dynamodbav:"Payload"`
+	CreatedAt     time.Time  `dynamodbav:"CreatedAt"`
+	UpdatedAt     time.Time  `dynamodbav:"UpdatedAt"`
+}
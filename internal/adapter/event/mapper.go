@@ -0,0 +1,59 @@
+package event
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"time"
+)
+
+func mapToEventEntity(event model.Event) Event {
+	return Event{
+		EventId:       event.EventId,
+		Type:          event.Type,
+		Status:        string(event.Status),
+		Error:         event.Error,
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt,
+		Payload:       event.Payload,
+		CreatedAt:     event.CreatedAt,
+		UpdatedAt:     time.Now(),
+	}
+}
+
+func mapToEventModel(event Event) model.Event {
+	return model.Event{
+		EventId:       event.EventId,
+		Type:          event.Type,
+		CreatedAt:     event.CreatedAt,
+		Payload:       event.Payload,
+		Status:        model.EventStatus(event.Status),
+		Error:         event.Error,
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt,
+	}
+}
+
+func mapToEventModelPtr(event *Event) *model.Event {
+	if event == nil {
+		return nil
+	}
+	res := mapToEventModel(*event)
+	return &res
+}
+
+func mapToEventsModel(events []Event) []model.Event {
+	res := make([]model.Event, 0, len(events))
+	for _, event := range events {
+		res = append(res, mapToEventModel(event))
+	}
+	return res
+}
+
+func mapToFilter(filter model.EventFilter) Filter {
+	return Filter{
+		Type:   filter.Type,
+		Status: string(filter.Status),
+		From:   filter.From,
+		To:     filter.To,
+		Limit:  filter.Limit,
+	}
+}
@@ -0,0 +1,340 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Repository interface {
+	CreateEvent(ctx context.Context, entity Event) error
+	GetEvent(ctx context.Context, eventId string) (*Event, error)
+	UpdateEvent(ctx context.Context, entity Event) error
+	GetEvents(ctx context.Context, filter Filter) ([]Event, error)
+	GetDueEvents(ctx context.Context, before time.Time, limit int) ([]Event, error)
+	ClaimEvent(ctx context.Context, entity Event, until time.Time) (bool, error)
+}
+
+// Filter selects events created in [From, To], empty Type and Status match any value
+type Filter struct {
+	Type   string
+	Status string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// eventLogIndex is the GSI1 index, events are partitioned there by the month they were created in
+const eventLogIndex = "GSI1"
+
+// deadLetterIndex is the GSI2 index, failed events waiting for a retry are sorted there by NextAttemptAt
+const deadLetterIndex = "GSI2"
+
+const deadLetterPartition = "DEAD_LETTER"
+
+type DynamoConfig struct {
+	Client       *dynamodb.Client
+	Table        string
+	QueryTimeout time.Duration
+}
+
+type dynamoRepository struct {
+	client       *dynamodb.Client
+	table        string
+	queryTimeout time.Duration
+}
+
+func NewDynamoRepository(config DynamoConfig) Repository {
+	return &dynamoRepository{
+		client:       config.Client,
+		table:        config.Table,
+		queryTimeout: config.QueryTimeout,
+	}
+}
+
+func (d *dynamoRepository) CreateEvent(ctx context.Context, entity Event) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	atr, err := attributevalue.MarshalMap(&entity)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo event entity")
+	}
+
+	pk := fmt.Sprintf("EVENT#%s", entity.EventId)
+	sk := fmt.Sprintf("EVENT#%s", entity.EventId)
+	atr["PK"] = &types.AttributeValueMemberS{Value: pk}
+	atr["SK"] = &types.AttributeValueMemberS{Value: sk}
+	atr["GSI1PK"] = &types.AttributeValueMemberS{Value: eventLogPartition(entity.CreatedAt)}
+	atr["GSI1SK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("%s#%s", eventLogTime(entity.CreatedAt), entity.EventId)}
+
+	input := &dynamodb.PutItemInput{
+		Item:                atr,
+		TableName:           aws.String(d.table),
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}
+
+	_, err = d.client.PutItem(ctx, input)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return model.NewEventAlreadyExistsErr(entity.EventId)
+		}
+		return errors.Wrapf(err, "failed to put dynamo event entity")
+	}
+
+	return nil
+}
+
+func (d *dynamoRepository) GetEvent(ctx context.Context, eventId string) (*Event, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	pk := fmt.Sprintf("EVENT#%s", eventId)
+	sk := fmt.Sprintf("EVENT#%s", eventId)
+
+	input := &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		TableName:      aws.String(d.table),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := d.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get dynamo event entity")
+	}
+
+	return unmarshalEventEntity(result)
+}
+
+func (d *dynamoRepository) UpdateEvent(ctx context.Context, entity Event) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(entity.UpdatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo event updated at")
+	}
+
+	pk := fmt.Sprintf("EVENT#%s", entity.EventId)
+	sk := fmt.Sprintf("EVENT#%s", entity.EventId)
+
+	update := "SET #status = :status, #error = :error, Attempts = :attempts, UpdatedAt = :updatedAt"
+	values := map[string]types.AttributeValue{
+		":status":    &types.AttributeValueMemberS{Value: entity.Status},
+		":error":     &types.AttributeValueMemberS{Value: entity.Error},
+		":attempts":  &types.AttributeValueMemberN{Value: strconv.Itoa(entity.Attempts)},
+		":updatedAt": updatedAt,
+	}
+	if entity.NextAttemptAt != nil {
+		// A scheduled retry puts the event into the dead letter index
+		update += ", NextAttemptAt = :nextAttemptAt, GSI2PK = :gsi2pk, GSI2SK = :gsi2sk"
+		values[":nextAttemptAt"] = unixTime(*entity.NextAttemptAt)
+		values[":gsi2pk"] = &types.AttributeValueMemberS{Value: deadLetterPartition}
+		values[":gsi2sk"] = &types.AttributeValueMemberS{Value: deadLetterKey(*entity.NextAttemptAt, entity.EventId)}
+	} else {
+		update += " REMOVE NextAttemptAt, GSI2PK, GSI2SK"
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		TableName:           aws.String(d.table),
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+			"#error":  "Error",
+		},
+		ExpressionAttributeValues: values,
+	}
+
+	_, err = d.client.UpdateItem(ctx, input)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update dynamo event entity")
+	}
+
+	return nil
+}
+
+// GetDueEvents returns the events whose retry is due at before, the earliest first
+func (d *dynamoRepository) GetDueEvents(ctx context.Context, before time.Time, limit int) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		IndexName:              aws.String(deadLetterIndex),
+		KeyConditionExpression: aws.String("GSI2PK = :pk AND GSI2SK <= :before"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: deadLetterPartition},
+			// '$' sorts after the '#' separator, so events due in the last second are included
+			":before": &types.AttributeValueMemberS{Value: eventLogTime(before) + "$"},
+		},
+	}
+
+	var entities []Event
+	paginator := dynamodb.NewQueryPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query due dynamo event entities")
+		}
+		var pageEntities []Event
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &pageEntities); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal dynamo event entities")
+		}
+		entities = append(entities, pageEntities...)
+		if limit > 0 && len(entities) >= limit {
+			return entities[:limit], nil
+		}
+	}
+
+	return entities, nil
+}
+
+// ClaimEvent moves the next retry of an event to until, it returns false if the retry was already
+// claimed or rescheduled by someone else
+func (d *dynamoRepository) ClaimEvent(ctx context.Context, entity Event, until time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	if entity.NextAttemptAt == nil {
+		return false, nil
+	}
+
+	pk := fmt.Sprintf("EVENT#%s", entity.EventId)
+	sk := fmt.Sprintf("EVENT#%s", entity.EventId)
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		TableName:           aws.String(d.table),
+		UpdateExpression:    aws.String("SET NextAttemptAt = :until, GSI2SK = :gsi2sk"),
+		ConditionExpression: aws.String("NextAttemptAt = :current"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":until":   unixTime(until),
+			":gsi2sk":  &types.AttributeValueMemberS{Value: deadLetterKey(until, entity.EventId)},
+			":current": unixTime(*entity.NextAttemptAt),
+		},
+	}
+
+	_, err := d.client.UpdateItem(ctx, input)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to claim dynamo event entity")
+	}
+
+	return true, nil
+}
+
+// GetEvents returns the newest events first, querying the monthly partitions from To back to From
+func (d *dynamoRepository) GetEvents(ctx context.Context, filter Filter) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: eventLogTime(filter.From)},
+		// '$' sorts after the '#' separator, so events of the last second are included
+		":to": &types.AttributeValueMemberS{Value: eventLogTime(filter.To) + "$"},
+	}
+	names := map[string]string{}
+	var conditions []string
+	if filter.Type != "" {
+		conditions = append(conditions, "#type = :type")
+		names["#type"] = "Type"
+		values[":type"] = &types.AttributeValueMemberS{Value: filter.Type}
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "#status = :status")
+		names["#status"] = "Status"
+		values[":status"] = &types.AttributeValueMemberS{Value: filter.Status}
+	}
+
+	var entities []Event
+	first := monthStart(filter.From)
+	for month := monthStart(filter.To); !month.Before(first); month = month.AddDate(0, -1, 0) {
+		values[":pk"] = &types.AttributeValueMemberS{Value: eventLogPartition(month)}
+
+		input := &dynamodb.QueryInput{
+			TableName:                 aws.String(d.table),
+			IndexName:                 aws.String(eventLogIndex),
+			KeyConditionExpression:    aws.String("GSI1PK = :pk AND GSI1SK BETWEEN :from AND :to"),
+			ExpressionAttributeValues: values,
+			ScanIndexForward:          aws.Bool(false),
+		}
+		if len(conditions) > 0 {
+			input.FilterExpression = aws.String(strings.Join(conditions, " AND "))
+			input.ExpressionAttributeNames = names
+		}
+
+		paginator := dynamodb.NewQueryPaginator(d.client, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to query dynamo event entities")
+			}
+			var pageEntities []Event
+			if err = attributevalue.UnmarshalListOfMaps(page.Items, &pageEntities); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal dynamo event entities")
+			}
+			entities = append(entities, pageEntities...)
+			if filter.Limit > 0 && len(entities) >= filter.Limit {
+				return entities[:filter.Limit], nil
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+func eventLogPartition(t time.Time) string {
+	return fmt.Sprintf("EVENT#%s", t.UTC().Format("2006-01"))
+}
+
+func eventLogTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func deadLetterKey(t time.Time, eventId string) string {
+	return fmt.Sprintf("%s#%s", eventLogTime(t), eventId)
+}
+
+// unixTime matches the unixtime encoding of NextAttemptAt in the entity
+func unixTime(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func unmarshalEventEntity(result *dynamodb.GetItemOutput) (*Event, error) {
+	if result.Item == nil {
+		return nil, nil
+	}
+	var entity Event
+	if err := attributevalue.UnmarshalMap(result.Item, &entity); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal dynamo event entity")
+	}
+	return &entity, nil
+}
@@ -0,0 +1,158 @@
+//go:build integration
+
+package event_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DenisBarabanshchikov/subscription/config"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/event"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+func TestDynamoRepository_CreateAndUpdateEvent(t *testing.T) {
+	repo := event.NewDynamoRepository(config.ProvideEventDynamoConfig())
+
+	// Create a unique test event.
+	eventId := fmt.Sprintf("evt_test-%d", time.Now().UnixNano())
+	evt := event.Event{
+		EventId:   eventId,
+		Type:      "invoice.paid",
+		Status:    "processing",
+		Attempts:  1,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	ctx := context.Background()
+
+	err := repo.CreateEvent(ctx, evt)
+	assert.NoError(t, err, "failed to create event")
+
+	// A redelivered event is rejected by the condition expression.
+	err = repo.CreateEvent(ctx, evt)
+	assert.IsType(t, model.EventAlreadyExistsErr{}, err)
+
+	evt.Status = "failed"
+	evt.Error = "subscription not found"
+	evt.Attempts = 2
+	evt.UpdatedAt = time.Now().UTC()
+	err = repo.UpdateEvent(ctx, evt)
+	assert.NoError(t, err, "failed to update event")
+
+	retrieved, err := repo.GetEvent(ctx, eventId)
+	assert.NoError(t, err, "failed to get event")
+	assert.NotNil(t, retrieved, "event not found")
+	assert.Equal(t, "failed", retrieved.Status)
+	assert.Equal(t, "subscription not found", retrieved.Error)
+	assert.Equal(t, 2, retrieved.Attempts)
+}
+
+func TestDynamoRepository_GetEvents(t *testing.T) {
+	repo := event.NewDynamoRepository(config.ProvideEventDynamoConfig())
+	ctx := context.Background()
+
+	// Events of a unique type in two consecutive months.
+	eventType := fmt.Sprintf("test.event.%d", time.Now().UnixNano())
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	previousMonth := createdAt.AddDate(0, -1, 0)
+	for i, at := range []time.Time{previousMonth, createdAt} {
+		err := repo.CreateEvent(ctx, event.Event{
+			EventId:   fmt.Sprintf("evt_test-%d-%d", time.Now().UnixNano(), i),
+			Type:      eventType,
+			Status:    "failed",
+			Attempts:  1,
+			Payload:   []byte(`{}`),
+			CreatedAt: at,
+			UpdatedAt: time.Now().UTC(),
+		})
+		assert.NoError(t, err, "failed to create event")
+	}
+
+	events, err := repo.GetEvents(ctx, event.Filter{
+		Type:   eventType,
+		Status: "failed",
+		From:   previousMonth,
+		To:     createdAt,
+		Limit:  10,
+	})
+	assert.NoError(t, err, "failed to get events")
+	assert.Len(t, events, 2)
+	// Newest first.
+	assert.True(t, events[0].CreatedAt.After(events[1].CreatedAt))
+	assert.Equal(t, []byte(`{}`), events[0].Payload)
+
+	// The status filter excludes all of them.
+	events, err = repo.GetEvents(ctx, event.Filter{
+		Type:   eventType,
+		Status: "processed",
+		From:   previousMonth,
+		To:     createdAt,
+	})
+	assert.NoError(t, err, "failed to get events")
+	assert.Empty(t, events)
+}
+
+func TestDynamoRepository_DeadLetter(t *testing.T) {
+	repo := event.NewDynamoRepository(config.ProvideEventDynamoConfig())
+	ctx := context.Background()
+
+	// A failed event due for a retry a second ago.
+	eventId := fmt.Sprintf("evt_test-%d", time.Now().UnixNano())
+	nextAttemptAt := time.Now().UTC().Add(-time.Second).Truncate(time.Second)
+	evt := event.Event{
+		EventId:   eventId,
+		Type:      "invoice.paid",
+		Status:    "processing",
+		Attempts:  1,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	err := repo.CreateEvent(ctx, evt)
+	assert.NoError(t, err, "failed to create event")
+
+	evt.Status = "failed"
+	evt.NextAttemptAt = &nextAttemptAt
+	err = repo.UpdateEvent(ctx, evt)
+	assert.NoError(t, err, "failed to update event")
+
+	due, err := repo.GetDueEvents(ctx, time.Now(), 0)
+	assert.NoError(t, err, "failed to get due events")
+	assert.True(t, containsEvent(due, eventId), "event is not due")
+
+	// Only the first claim succeeds.
+	claimed, err := repo.ClaimEvent(ctx, evt, time.Now().Add(time.Hour))
+	assert.NoError(t, err, "failed to claim event")
+	assert.True(t, claimed)
+	claimed, err = repo.ClaimEvent(ctx, evt, time.Now().Add(time.Hour))
+	assert.NoError(t, err, "failed to claim event")
+	assert.False(t, claimed)
+
+	due, err = repo.GetDueEvents(ctx, time.Now(), 0)
+	assert.NoError(t, err, "failed to get due events")
+	assert.False(t, containsEvent(due, eventId), "claimed event is still due")
+
+	// A processed event leaves the dead letter index.
+	evt.Status = "processed"
+	evt.NextAttemptAt = nil
+	err = repo.UpdateEvent(ctx, evt)
+	assert.NoError(t, err, "failed to update event")
+
+	due, err = repo.GetDueEvents(ctx, time.Now().Add(2*time.Hour), 0)
+	assert.NoError(t, err, "failed to get due events")
+	assert.False(t, containsEvent(due, eventId), "processed event is still due")
+}
+
+func containsEvent(events []event.Event, eventId string) bool {
+	for _, e := range events {
+		if e.EventId == eventId {
+			return true
+		}
+	}
+	return false
+}
@@ -5,15 +5,35 @@ import (
 	"fmt"
 	"github.com/DenisBarabanshchikov/subscription/internal/model"
 	"github.com/DenisBarabanshchikov/subscription/internal/port"
+	"github.com/pkg/errors"
+	"github.com/stripe/stripe-go/v74"
+	"strings"
 )
 
+// AddOnConfig maps the add-ons on sale to their prices per currency
+type AddOnConfig struct {
+	Prices map[string]map[string]string
+}
+
+// PlanConfig selects the prices of the catalog plans, Environment is the key of the prices
+// to use, such as test or live
+type PlanConfig struct {
+	Environment string
+}
+
 type adapter struct {
-	api Api
+	api        Api
+	addOns     AddOnConfig
+	plans      port.Plan
+	planConfig PlanConfig
 }
 
-func NewAdapter(api Api) port.PaymentProvider {
+func NewAdapter(api Api, addOns AddOnConfig, plans port.Plan, planConfig PlanConfig) port.PaymentProvider {
 	return &adapter{
-		api: api,
+		api:        api,
+		addOns:     addOns,
+		plans:      plans,
+		planConfig: planConfig,
 	}
 }
 
@@ -21,27 +41,223 @@ func (a *adapter) CreateCustomer(ctx context.Context, email string) (string, err
 	return a.api.CreateCustomer(ctx, email)
 }
 
-func (a *adapter) SubscribeCustomer(ctx context.Context, customer model.Customer, plan string) (string, error) {
-	price, err := a.getPriceByPlan(ctx, plan)
+func (a *adapter) SubscribeCustomer(ctx context.Context, customer model.Customer, plan string, interval model.BillingInterval, currency string, quantity int, trial *model.Trial) (model.ProviderSubscription, error) {
+	price, priceCurrency, err := a.getPlanPrice(ctx, plan, interval, currency)
+	if err != nil {
+		return model.ProviderSubscription{}, err
+	}
+	var trialDays int64
+	var trialEndBehavior string
+	if trial != nil {
+		trialDays = int64(trial.Days)
+		trialEndBehavior = string(trial.EndBehavior)
+	}
+	subscription, err := a.api.SubscribeCustomer(ctx, customer, price, int64(quantity), trialDays, trialEndBehavior)
+	if err != nil {
+		return model.ProviderSubscription{}, mapCurrencyErr(err, model.NewCurrencyMismatchErr(customer.CustomerId, priceCurrency))
+	}
+	return mapToProviderSubscription(subscription)
+}
+
+func (a *adapter) GetSubscriptionStatus(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error) {
+	status, err := a.api.GetSubscriptionStatus(ctx, subscriptionId)
 	if err != nil {
 		return "", err
 	}
-	return a.api.SubscribeCustomer(ctx, customer, price)
+	return mapToSubscriptionStatus(stripe.SubscriptionStatus(status))
 }
 
-func (a *adapter) GetSubscriptionStatus(ctx context.Context, subscriptionId string) (string, error) {
-	return a.api.GetSubscriptionStatus(ctx, subscriptionId)
+func (a *adapter) GetSubscriptionCurrency(ctx context.Context, subscriptionId string) (string, error) {
+	return a.api.GetSubscriptionCurrency(ctx, subscriptionId)
 }
 
-func (a *adapter) getPriceByPlan(_ context.Context, plan string) (string, error) {
-	switch plan {
-	case "Core":
-		return "price_1QtWUdIGaC2gk9oobOvUwioa", nil
-	case "Growth":
-		return "price_1QtWcBIGaC2gk9ookwUgcQPj", nil
-	case "Premium":
-		return "price_1QtWcWIGaC2gk9ooNnWu1RJi", nil
+func (a *adapter) CancelSubscription(ctx context.Context, subscriptionId string, cancellation model.Cancellation) (model.SubscriptionStatus, error) {
+	var status string
+	var err error
+	switch cancellation.Mode {
+	case model.CancelModeImmediately:
+		status, err = a.api.CancelSubscription(ctx, subscriptionId, cancellation.Reason)
+	case model.CancelModeAtPeriodEnd:
+		status, err = a.api.CancelSubscriptionAtPeriodEnd(ctx, subscriptionId, cancellation.Reason)
 	default:
-		return "", fmt.Errorf("unknown plan: %s", plan)
+		return "", fmt.Errorf("unknown cancel mode: %s", cancellation.Mode)
+	}
+	if err != nil {
+		return "", err
+	}
+	return mapToSubscriptionStatus(stripe.SubscriptionStatus(status))
+}
+
+func (a *adapter) PauseSubscription(ctx context.Context, subscriptionId string, pause model.Pause) (model.SubscriptionStatus, error) {
+	status, err := a.api.PauseSubscription(ctx, subscriptionId, string(pause.Behavior), pause.ResumesAt)
+	if err != nil {
+		return "", err
+	}
+	return mapToSubscriptionStatus(stripe.SubscriptionStatus(status))
+}
+
+func (a *adapter) ResumeSubscription(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error) {
+	status, err := a.api.ResumeSubscription(ctx, subscriptionId)
+	if err != nil {
+		return "", err
+	}
+	return mapToSubscriptionStatus(stripe.SubscriptionStatus(status))
+}
+
+func (a *adapter) ChangePlan(ctx context.Context, subscriptionId string, change model.PlanChange) error {
+	price, priceCurrency, err := a.getPlanPrice(ctx, change.Plan, change.Interval, change.Currency)
+	if err != nil {
+		return err
+	}
+	err = a.api.ChangeSubscriptionPrice(ctx, subscriptionId, price, string(change.ProrationBehavior))
+	return mapCurrencyErr(err, model.NewSubscriptionCurrencyMismatchErr(subscriptionId, priceCurrency))
+}
+
+func (a *adapter) ChangeQuantity(ctx context.Context, subscriptionId string, change model.QuantityChange) error {
+	return a.api.ChangeSubscriptionQuantity(ctx, subscriptionId, int64(change.Quantity), string(change.ProrationBehavior))
+}
+
+func (a *adapter) AddAddOn(ctx context.Context, subscriptionId, addOn, currency string, quantity int) (string, error) {
+	prices, ok := a.addOns.Prices[addOn]
+	if !ok || len(prices) == 0 {
+		return "", model.NewValidationErr(fmt.Sprintf("unknown add-on '%s'", addOn))
+	}
+	price := prices[currency]
+	if price == "" {
+		return "", model.NewValidationErr(fmt.Sprintf("add-on '%s' is not sold in '%s'", addOn, currency))
+	}
+	itemId, err := a.api.AddSubscriptionItem(ctx, subscriptionId, price, int64(quantity), addOn)
+	if err != nil {
+		return "", mapCurrencyErr(err, model.NewSubscriptionCurrencyMismatchErr(subscriptionId, currency))
+	}
+	return itemId, nil
+}
+
+func (a *adapter) RemoveAddOn(ctx context.Context, externalItemId string) error {
+	return a.api.DeleteSubscriptionItem(ctx, externalItemId)
+}
+
+func (a *adapter) CreateCheckoutSession(ctx context.Context, customer model.Customer, checkout model.Checkout) (model.CheckoutSession, error) {
+	price, priceCurrency, err := a.getPlanPrice(ctx, checkout.Plan, checkout.Interval, checkout.Currency)
+	if err != nil {
+		return model.CheckoutSession{}, err
+	}
+	session, err := a.api.CreateCheckoutSession(ctx, customer, price, checkout.Plan, string(checkout.Interval), checkout.SuccessUrl, checkout.CancelUrl)
+	if err != nil {
+		return model.CheckoutSession{}, mapCurrencyErr(err, model.NewCurrencyMismatchErr(customer.CustomerId, priceCurrency))
+	}
+	return model.CheckoutSession{
+		ExternalSessionId: session.ID,
+		Url:               session.URL,
+	}, nil
+}
+
+func (a *adapter) CreatePortalSession(ctx context.Context, customer model.Customer) (string, error) {
+	return a.api.CreatePortalSession(ctx, customer.ExternalCustomerId)
+}
+
+func (a *adapter) CreateSetupIntent(ctx context.Context, customer model.Customer) (model.PaymentConfirmation, error) {
+	intent, err := a.api.CreateSetupIntent(ctx, customer.ExternalCustomerId)
+	if err != nil {
+		return model.PaymentConfirmation{}, err
+	}
+	return *mapToSetupConfirmation(&intent), nil
+}
+
+func (a *adapter) PaymentMethods(ctx context.Context, customer model.Customer) ([]model.PaymentMethod, error) {
+	methods, err := a.api.ListPaymentMethods(ctx, customer.ExternalCustomerId)
+	if err != nil {
+		return nil, err
+	}
+	defaultId, err := a.api.GetDefaultPaymentMethod(ctx, customer.ExternalCustomerId)
+	if err != nil {
+		return nil, err
+	}
+	return mapToPaymentMethodsModel(methods, defaultId), nil
+}
+
+func (a *adapter) SetDefaultPaymentMethod(ctx context.Context, customer model.Customer, paymentMethodId string) error {
+	return a.api.SetDefaultPaymentMethod(ctx, customer.ExternalCustomerId, paymentMethodId)
+}
+
+func (a *adapter) DetachPaymentMethod(ctx context.Context, paymentMethodId string) error {
+	return a.api.DetachPaymentMethod(ctx, paymentMethodId)
+}
+
+func (a *adapter) PreviewPlanChange(ctx context.Context, subscriptionId string, change model.PlanChange) (model.InvoicePreview, error) {
+	price, priceCurrency, err := a.getPlanPrice(ctx, change.Plan, change.Interval, change.Currency)
+	if err != nil {
+		return model.InvoicePreview{}, err
+	}
+	invoice, err := a.api.PreviewSubscriptionPrice(ctx, subscriptionId, price, string(change.ProrationBehavior))
+	if err != nil {
+		return model.InvoicePreview{}, mapCurrencyErr(err, model.NewSubscriptionCurrencyMismatchErr(subscriptionId, priceCurrency))
+	}
+	return mapToInvoicePreview(invoice), nil
+}
+
+// GetPlanCatalog reads the plans on sale in the configured environment
+func (a *adapter) GetPlanCatalog(ctx context.Context) (model.ProviderCatalog, error) {
+	products, err := a.api.ListProducts(ctx)
+	if err != nil {
+		return model.ProviderCatalog{}, err
+	}
+	prices, err := a.api.ListPrices(ctx)
+	if err != nil {
+		return model.ProviderCatalog{}, err
+	}
+	return mapToProviderCatalog(products, prices, a.planConfig.Environment), nil
+}
+
+func (a *adapter) ConstructEvent(ctx context.Context, payload []byte, signature string) (model.Event, error) {
+	event, err := a.api.ConstructEvent(ctx, payload, signature)
+	if err != nil {
+		return model.Event{}, err
+	}
+	return mapToVerifiedEventModel(event, payload)
+}
+
+func (a *adapter) ParseEvent(ctx context.Context, payload []byte) (model.Event, error) {
+	event, err := a.api.ParseEvent(ctx, payload)
+	if err != nil {
+		return model.Event{}, err
+	}
+	return mapToVerifiedEventModel(event, payload)
+}
+
+// getPlanPrice resolves the price of the plan for the billing interval and currency in the configured
+// environment and returns it with its currency. Without a currency the main currency of the plan is
+// used, archived plans are unknown.
+func (a *adapter) getPlanPrice(ctx context.Context, planId string, interval model.BillingInterval, currency string) (string, string, error) {
+	plan, err := a.plans.GetPlan(ctx, planId)
+	if err != nil {
+		return "", "", err
+	}
+	if plan == nil || plan.Archived {
+		return "", "", model.NewValidationErr(fmt.Sprintf("unknown plan: %s", planId))
+	}
+	if plan.Price(interval, "") == nil {
+		return "", "", model.NewValidationErr(fmt.Sprintf("plan %s is not billed per %s", planId, interval))
+	}
+	planPrice := plan.Price(interval, currency)
+	if planPrice == nil {
+		return "", "", model.NewValidationErr(fmt.Sprintf("plan %s is not sold per %s in %s", planId, interval, currency))
+	}
+	price := planPrice.ExternalPriceIds[a.planConfig.Environment]
+	if price == "" {
+		return "", "", fmt.Errorf("plan %s has no %s %s price in the %s environment", planId, interval, planPrice.Currency, a.planConfig.Environment)
+	}
+	return price, planPrice.Currency, nil
+}
+
+// mapCurrencyErr replaces the error Stripe returns for a price in another currency than the one the customer
+// is billed in by mismatchErr. It has no error code of its own, only its message tells it from other invalid
+// requests.
+func mapCurrencyErr(err error, mismatchErr model.CurrencyMismatchErr) error {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeInvalidRequest &&
+		strings.Contains(stripeErr.Msg, "combine currencies") {
+		return mismatchErr
 	}
+	return err
 }
@@ -4,11 +4,14 @@ package stripe_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	stripeSdk "github.com/stripe/stripe-go/v74"
 
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
 	"github.com/DenisBarabanshchikov/subscription/internal/model"
@@ -26,9 +29,9 @@ func (m *mockApi) CreateCustomer(ctx context.Context, email string) (string, err
 	return args.String(0), args.Error(1)
 }
 
-func (m *mockApi) SubscribeCustomer(ctx context.Context, customer model.Customer, price string) (string, error) {
-	args := m.Called(ctx, customer, price)
-	return args.String(0), args.Error(1)
+func (m *mockApi) SubscribeCustomer(ctx context.Context, customer model.Customer, price string, quantity, trialDays int64, trialEndBehavior string) (stripeSdk.Subscription, error) {
+	args := m.Called(ctx, customer, price, quantity, trialDays, trialEndBehavior)
+	return args.Get(0).(stripeSdk.Subscription), args.Error(1)
 }
 
 func (m *mockApi) GetSubscriptionStatus(ctx context.Context, subscriptionId string) (string, error) {
@@ -36,10 +39,168 @@ func (m *mockApi) GetSubscriptionStatus(ctx context.Context, subscriptionId stri
 	return args.String(0), args.Error(1)
 }
 
+func (m *mockApi) GetSubscriptionCurrency(ctx context.Context, subscriptionId string) (string, error) {
+	args := m.Called(ctx, subscriptionId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) CancelSubscription(ctx context.Context, subscriptionId, reason string) (string, error) {
+	args := m.Called(ctx, subscriptionId, reason)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) CancelSubscriptionAtPeriodEnd(ctx context.Context, subscriptionId, reason string) (string, error) {
+	args := m.Called(ctx, subscriptionId, reason)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) PauseSubscription(ctx context.Context, subscriptionId, behavior string, resumesAt *time.Time) (string, error) {
+	args := m.Called(ctx, subscriptionId, behavior, resumesAt)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) ResumeSubscription(ctx context.Context, subscriptionId string) (string, error) {
+	args := m.Called(ctx, subscriptionId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) ChangeSubscriptionQuantity(ctx context.Context, subscriptionId string, quantity int64, prorationBehavior string) error {
+	args := m.Called(ctx, subscriptionId, quantity, prorationBehavior)
+	return args.Error(0)
+}
+
+func (m *mockApi) AddSubscriptionItem(ctx context.Context, subscriptionId, price string, quantity int64, addOn string) (string, error) {
+	args := m.Called(ctx, subscriptionId, price, quantity, addOn)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) DeleteSubscriptionItem(ctx context.Context, itemId string) error {
+	args := m.Called(ctx, itemId)
+	return args.Error(0)
+}
+
+func (m *mockApi) ChangeSubscriptionPrice(ctx context.Context, subscriptionId, price, prorationBehavior string) error {
+	args := m.Called(ctx, subscriptionId, price, prorationBehavior)
+	return args.Error(0)
+}
+
+func (m *mockApi) PreviewSubscriptionPrice(ctx context.Context, subscriptionId, price, prorationBehavior string) (stripeSdk.Invoice, error) {
+	args := m.Called(ctx, subscriptionId, price, prorationBehavior)
+	return args.Get(0).(stripeSdk.Invoice), args.Error(1)
+}
+
+func (m *mockApi) CreateCheckoutSession(ctx context.Context, customer model.Customer, price, plan, interval, successUrl, cancelUrl string) (stripeSdk.CheckoutSession, error) {
+	args := m.Called(ctx, customer, price, plan, interval, successUrl, cancelUrl)
+	return args.Get(0).(stripeSdk.CheckoutSession), args.Error(1)
+}
+
+func (m *mockApi) CreatePortalSession(ctx context.Context, externalCustomerId string) (string, error) {
+	args := m.Called(ctx, externalCustomerId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) CreateSetupIntent(ctx context.Context, externalCustomerId string) (stripeSdk.SetupIntent, error) {
+	args := m.Called(ctx, externalCustomerId)
+	return args.Get(0).(stripeSdk.SetupIntent), args.Error(1)
+}
+
+func (m *mockApi) ListPaymentMethods(ctx context.Context, externalCustomerId string) ([]stripeSdk.PaymentMethod, error) {
+	args := m.Called(ctx, externalCustomerId)
+	return args.Get(0).([]stripeSdk.PaymentMethod), args.Error(1)
+}
+
+func (m *mockApi) GetDefaultPaymentMethod(ctx context.Context, externalCustomerId string) (string, error) {
+	args := m.Called(ctx, externalCustomerId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockApi) SetDefaultPaymentMethod(ctx context.Context, externalCustomerId, paymentMethodId string) error {
+	args := m.Called(ctx, externalCustomerId, paymentMethodId)
+	return args.Error(0)
+}
+
+func (m *mockApi) DetachPaymentMethod(ctx context.Context, paymentMethodId string) error {
+	args := m.Called(ctx, paymentMethodId)
+	return args.Error(0)
+}
+
+func (m *mockApi) ListProducts(ctx context.Context) ([]stripeSdk.Product, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]stripeSdk.Product), args.Error(1)
+}
+
+func (m *mockApi) ListPrices(ctx context.Context) ([]stripeSdk.Price, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]stripeSdk.Price), args.Error(1)
+}
+
+func (m *mockApi) ConstructEvent(ctx context.Context, payload []byte, signature string) (stripeSdk.Event, error) {
+	args := m.Called(ctx, payload, signature)
+	return args.Get(0).(stripeSdk.Event), args.Error(1)
+}
+
+func (m *mockApi) ParseEvent(ctx context.Context, payload []byte) (stripeSdk.Event, error) {
+	args := m.Called(ctx, payload)
+	return args.Get(0).(stripeSdk.Event), args.Error(1)
+}
+
+var addOnConfig = stripe.AddOnConfig{Prices: map[string]map[string]string{"ExtraStorage": {"usd": "price_storage", "eur": "price_storage_eur"}}}
+
+var planConfig = stripe.PlanConfig{Environment: "test"}
+
+// planCatalog is an in-memory plan catalog
+type planCatalog map[string]model.Plan
+
+func (c planCatalog) GetPlan(_ context.Context, planId string) (*model.Plan, error) {
+	plan, ok := c[planId]
+	if !ok {
+		return nil, nil
+	}
+	return &plan, nil
+}
+
+func (c planCatalog) GetPlans(_ context.Context) ([]model.Plan, error) {
+	res := make([]model.Plan, 0, len(c))
+	for _, plan := range c {
+		res = append(res, plan)
+	}
+	return res, nil
+}
+
+func (c planCatalog) CreatePlan(_ context.Context, plan model.Plan) error {
+	if _, ok := c[plan.PlanId]; ok {
+		return model.NewPlanAlreadyExistsErr(plan.PlanId)
+	}
+	c[plan.PlanId] = plan
+	return nil
+}
+
+func (c planCatalog) PutPlan(_ context.Context, plan model.Plan) error {
+	c[plan.PlanId] = plan
+	return nil
+}
+
+// monthly returns the monthly price of a plan with its provider price per environment
+func monthly(priceIds map[string]string) model.PlanPrice {
+	return model.PlanPrice{Interval: model.BillingIntervalMonth, Currency: "usd", ExternalPriceIds: priceIds}
+}
+
+var plans = planCatalog{
+	"Core": {PlanId: "Core", Prices: []model.PlanPrice{
+		monthly(map[string]string{"test": "price_1QtWUdIGaC2gk9oobOvUwioa"}),
+		{Interval: model.BillingIntervalMonth, Currency: "eur", ExternalPriceIds: map[string]string{"test": "price_core_eur"}},
+		{Interval: model.BillingIntervalYear, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_core_yearly"}},
+	}},
+	"Growth":   {PlanId: "Growth", Prices: []model.PlanPrice{monthly(map[string]string{"test": "price_1QtWcBIGaC2gk9ookwUgcQPj"})}},
+	"Premium":  {PlanId: "Premium", Prices: []model.PlanPrice{monthly(map[string]string{"test": "price_1QtWcWIGaC2gk9ooNnWu1RJi"})}},
+	"Legacy":   {PlanId: "Legacy", Prices: []model.PlanPrice{monthly(map[string]string{"test": "price_legacy"})}, Archived: true},
+	"LiveOnly": {PlanId: "LiveOnly", Prices: []model.PlanPrice{monthly(map[string]string{"live": "price_live"})}},
+}
+
 // TestNewAdapter checks that NewAdapter returns a port.PaymentProvider implementation
 func TestNewAdapter(t *testing.T) {
 	mockAPI := new(mockApi)
-	provider := stripe.NewAdapter(mockAPI)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
 
 	assert.Implements(t, (*port.PaymentProvider)(nil), provider)
 }
@@ -48,7 +209,7 @@ func TestNewAdapter(t *testing.T) {
 func TestCreateCustomer(t *testing.T) {
 	ctx := context.Background()
 	mockAPI := new(mockApi)
-	provider := stripe.NewAdapter(mockAPI)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
 
 	mockAPI.
 		On("CreateCustomer", ctx, "test@example.com").
@@ -66,7 +227,7 @@ func TestCreateCustomer(t *testing.T) {
 func TestSubscribeCustomer(t *testing.T) {
 	ctx := context.Background()
 	mockAPI := new(mockApi)
-	provider := stripe.NewAdapter(mockAPI)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
 
 	customer := model.Customer{
 		CustomerId:         "customer-id-1",
@@ -75,26 +236,171 @@ func TestSubscribeCustomer(t *testing.T) {
 
 	// 1. Test known plan
 	mockAPI.
-		On("SubscribeCustomer", ctx, customer, "price_1QtWUdIGaC2gk9oobOvUwioa").
-		Return("sub_9876", nil).
+		On("SubscribeCustomer", ctx, customer, "price_1QtWUdIGaC2gk9oobOvUwioa", int64(5), int64(0), "").
+		Return(stripeSdk.Subscription{
+			ID:     "sub_9876",
+			Status: stripeSdk.SubscriptionStatusIncomplete,
+			LatestInvoice: &stripeSdk.Invoice{
+				PaymentIntent: &stripeSdk.PaymentIntent{
+					ClientSecret: "pi_123_secret_456",
+					Status:       stripeSdk.PaymentIntentStatusRequiresAction,
+					NextAction: &stripeSdk.PaymentIntentNextAction{
+						Type:          stripeSdk.PaymentIntentNextActionTypeRedirectToURL,
+						RedirectToURL: &stripeSdk.PaymentIntentNextActionRedirectToURL{URL: "https://hooks.stripe.com/3ds"},
+					},
+				},
+			},
+		}, nil).
 		Once()
 
-	subID, err := provider.SubscribeCustomer(ctx, customer, "Core")
+	sub, err := provider.SubscribeCustomer(ctx, customer, "Core", model.BillingIntervalMonth, "", 5, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, "sub_9876", subID)
+	assert.Equal(t, "sub_9876", sub.ExternalSubscriptionId)
+	assert.Equal(t, model.SubscriptionStatusIncomplete, sub.Status)
+	assert.Nil(t, sub.TrialEnd)
+	assert.Equal(t, &model.PaymentConfirmation{
+		Intent:       model.PaymentIntentTypePayment,
+		ClientSecret: "pi_123_secret_456",
+		Status:       "requires_action",
+		NextAction:   "redirect_to_url",
+		RedirectUrl:  "https://hooks.stripe.com/3ds",
+	}, sub.Payment)
 	mockAPI.AssertExpectations(t)
 
 	// 2. Test unknown plan -> expect error
-	_, err = provider.SubscribeCustomer(ctx, customer, "NonExistentPlan")
+	_, err = provider.SubscribeCustomer(ctx, customer, "NonExistentPlan", model.BillingIntervalMonth, "", 1, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown plan: NonExistentPlan")
 }
 
+// TestSubscribeCustomerPlanWithoutPrice checks archived plans and plans without a price in the environment
+func TestSubscribeCustomerPlanWithoutPrice(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"}
+
+	_, err := provider.SubscribeCustomer(ctx, customer, "Legacy", model.BillingIntervalMonth, "", 1, nil)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	_, err = provider.SubscribeCustomer(ctx, customer, "LiveOnly", model.BillingIntervalMonth, "", 1, nil)
+	assert.EqualError(t, err, "plan LiveOnly has no month usd price in the test environment")
+
+	mockAPI.AssertNotCalled(t, "SubscribeCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestSubscribeCustomerInterval checks that the price of the billing interval is used
+func TestSubscribeCustomerInterval(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"}
+
+	mockAPI.
+		On("SubscribeCustomer", ctx, customer, "price_core_yearly", int64(1), int64(0), "").
+		Return(stripeSdk.Subscription{ID: "sub_9876", Status: stripeSdk.SubscriptionStatusIncomplete}, nil).
+		Once()
+
+	sub, err := provider.SubscribeCustomer(ctx, customer, "Core", model.BillingIntervalYear, "", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "sub_9876", sub.ExternalSubscriptionId)
+
+	// Growth isn't billed quarterly
+	_, err = provider.SubscribeCustomer(ctx, customer, "Growth", model.BillingIntervalQuarter, "", 1, nil)
+	assert.IsType(t, model.ValidationErr{}, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestSubscribeCustomerCurrency checks that the price in the currency of the customer is used
+func TestSubscribeCustomerCurrency(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"}
+
+	mockAPI.
+		On("SubscribeCustomer", ctx, customer, "price_core_eur", int64(1), int64(0), "").
+		Return(stripeSdk.Subscription{ID: "sub_9876", Status: stripeSdk.SubscriptionStatusIncomplete, Currency: "eur"}, nil).
+		Once()
+
+	sub, err := provider.SubscribeCustomer(ctx, customer, "Core", model.BillingIntervalMonth, "eur", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "eur", sub.Currency)
+
+	// Core isn't sold yearly in eur
+	_, err = provider.SubscribeCustomer(ctx, customer, "Core", model.BillingIntervalYear, "eur", 1, nil)
+	assert.EqualError(t, err, "plan Core is not sold per year in eur")
+	mockAPI.AssertExpectations(t)
+}
+
+// TestSubscribeCustomerCurrencyMismatch checks that Stripe rejecting a second currency on the customer is
+// told from other failures
+func TestSubscribeCustomerCurrencyMismatch(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"}
+
+	mockAPI.
+		On("SubscribeCustomer", ctx, customer, "price_core_eur", int64(1), int64(0), "").
+		Return(stripeSdk.Subscription{}, &stripeSdk.Error{
+			Type: stripeSdk.ErrorTypeInvalidRequest,
+			Msg:  "You cannot combine currencies on a single customer. This customer has an active subscription, subscription schedule, discount, quote, or invoice item with currency usd.",
+		}).
+		Once()
+
+	_, err := provider.SubscribeCustomer(ctx, customer, "Core", model.BillingIntervalMonth, "eur", 1, nil)
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
+	assert.EqualError(t, err, "customer 'customer-id-1' is billed in another currency than 'eur'")
+	mockAPI.AssertExpectations(t)
+}
+
+// TestSubscribeCustomerWithTrial checks that the trial is passed on and its end is mapped
+func TestSubscribeCustomerWithTrial(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{
+		CustomerId:         "customer-id-1",
+		ExternalCustomerId: "external-customer-id-1",
+	}
+
+	mockAPI.
+		On("SubscribeCustomer", ctx, customer, "price_1QtWcBIGaC2gk9ookwUgcQPj", int64(1), int64(14), "pause").
+		Return(stripeSdk.Subscription{
+			ID:       "sub_9876",
+			Status:   stripeSdk.SubscriptionStatusTrialing,
+			TrialEnd: 1700000000,
+			PendingSetupIntent: &stripeSdk.SetupIntent{
+				ClientSecret: "seti_123_secret_456",
+				Status:       stripeSdk.SetupIntentStatusRequiresPaymentMethod,
+			},
+		}, nil).
+		Once()
+
+	sub, err := provider.SubscribeCustomer(ctx, customer, "Growth", model.BillingIntervalMonth, "", 1, &model.Trial{Days: 14, EndBehavior: model.TrialEndBehaviorPause})
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusTrialing, sub.Status)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), *sub.TrialEnd)
+	// Nothing to pay during the trial, the setup intent collects a payment method
+	assert.Equal(t, &model.PaymentConfirmation{
+		Intent:       model.PaymentIntentTypeSetup,
+		ClientSecret: "seti_123_secret_456",
+		Status:       "requires_payment_method",
+	}, sub.Payment)
+	mockAPI.AssertExpectations(t)
+}
+
 // TestSubscribeCustomerAPIFailure checks when api.SubscribeCustomer returns an error
 func TestSubscribeCustomerAPIFailure(t *testing.T) {
 	ctx := context.Background()
 	mockAPI := new(mockApi)
-	provider := stripe.NewAdapter(mockAPI)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
 
 	customer := model.Customer{
 		CustomerId:         "customer-id-1",
@@ -103,11 +409,11 @@ func TestSubscribeCustomerAPIFailure(t *testing.T) {
 
 	// For example, "Premium" plan
 	mockAPI.
-		On("SubscribeCustomer", ctx, customer, "price_1QtWcWIGaC2gk9ooNnWu1RJi").
-		Return("", errors.New("api failure")).
+		On("SubscribeCustomer", ctx, customer, "price_1QtWcWIGaC2gk9ooNnWu1RJi", int64(1), int64(0), "").
+		Return(stripeSdk.Subscription{}, errors.New("api failure")).
 		Once()
 
-	_, err := provider.SubscribeCustomer(ctx, customer, "Premium")
+	_, err := provider.SubscribeCustomer(ctx, customer, "Premium", model.BillingIntervalMonth, "", 1, nil)
 	assert.Error(t, err)
 	assert.Equal(t, "api failure", err.Error())
 	mockAPI.AssertExpectations(t)
@@ -117,7 +423,7 @@ func TestSubscribeCustomerAPIFailure(t *testing.T) {
 func TestGetSubscriptionStatus(t *testing.T) {
 	ctx := context.Background()
 	mockAPI := new(mockApi)
-	provider := stripe.NewAdapter(mockAPI)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
 
 	mockAPI.
 		On("GetSubscriptionStatus", ctx, "sub_123").
@@ -127,6 +433,619 @@ func TestGetSubscriptionStatus(t *testing.T) {
 	status, err := provider.GetSubscriptionStatus(ctx, "sub_123")
 
 	assert.NoError(t, err)
-	assert.Equal(t, "active", status)
+	assert.Equal(t, model.SubscriptionStatusActive, status)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestGetSubscriptionStatusUnknown checks that a status outside of the model set is rejected
+func TestGetSubscriptionStatusUnknown(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("GetSubscriptionStatus", ctx, "sub_123").
+		Return("suspended", nil).
+		Once()
+
+	status, err := provider.GetSubscriptionStatus(ctx, "sub_123")
+
+	assert.Error(t, err)
+	assert.Empty(t, status)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestCancelSubscription checks that each cancel mode calls its own api method
+func TestCancelSubscription(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("CancelSubscription", ctx, "sub_123", "too expensive").
+		Return("canceled", nil).
+		Once()
+	mockAPI.
+		On("CancelSubscriptionAtPeriodEnd", ctx, "sub_456", "").
+		Return("active", nil).
+		Once()
+
+	status, err := provider.CancelSubscription(ctx, "sub_123", model.Cancellation{Mode: model.CancelModeImmediately, Reason: "too expensive"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusCanceled, status)
+
+	status, err = provider.CancelSubscription(ctx, "sub_456", model.Cancellation{Mode: model.CancelModeAtPeriodEnd})
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusActive, status)
+
+	mockAPI.AssertExpectations(t)
+}
+
+// TestPauseSubscription checks that the pause is passed on to the api
+func TestPauseSubscription(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	resumesAt := time.Now().Add(24 * time.Hour)
+	mockAPI.
+		On("PauseSubscription", ctx, "sub_123", "mark_uncollectible", &resumesAt).
+		Return("paused", nil).
+		Once()
+
+	status, err := provider.PauseSubscription(ctx, "sub_123", model.Pause{ResumesAt: &resumesAt, Behavior: model.PauseBehaviorMarkUncollectible})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPaused, status)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestChangePlan checks that the plan is swapped for its price
+func TestChangePlan(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ChangeSubscriptionPrice", ctx, "sub_123", "price_1QtWcWIGaC2gk9ooNnWu1RJi", "always_invoice").
+		Return(nil).
+		Once()
+
+	err := provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Premium", Interval: model.BillingIntervalMonth, ProrationBehavior: model.ProrationBehaviorAlwaysInvoice})
+
+	assert.NoError(t, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestChangePlanInterval checks that switching the interval on the same plan swaps the price
+func TestChangePlanInterval(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ChangeSubscriptionPrice", ctx, "sub_123", "price_core_yearly", "create_prorations").
+		Return(nil).
+		Once()
+
+	err := provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Core", Interval: model.BillingIntervalYear, ProrationBehavior: model.ProrationBehaviorCreateProrations})
+
+	assert.NoError(t, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestChangePlanCurrency checks that the new price is in the currency of the subscription
+func TestChangePlanCurrency(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ChangeSubscriptionPrice", ctx, "sub_123", "price_core_eur", "create_prorations").
+		Return(nil).
+		Once()
+
+	err := provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Core", Interval: model.BillingIntervalMonth, Currency: "eur", ProrationBehavior: model.ProrationBehaviorCreateProrations})
+	assert.NoError(t, err)
+
+	// Premium has no eur price
+	err = provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Premium", Interval: model.BillingIntervalMonth, Currency: "eur", ProrationBehavior: model.ProrationBehaviorCreateProrations})
+	assert.IsType(t, model.ValidationErr{}, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestChangePlanCurrencyMismatch checks that Stripe rejecting a price in another currency than the one of
+// the subscription is told from other failures
+func TestChangePlanCurrencyMismatch(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ChangeSubscriptionPrice", ctx, "sub_123", "price_core_eur", "create_prorations").
+		Return(&stripeSdk.Error{
+			Type: stripeSdk.ErrorTypeInvalidRequest,
+			Msg:  "You cannot combine currencies on a single customer. This customer has an active subscription, subscription schedule, discount, quote, or invoice item with currency usd.",
+		}).
+		Once()
+
+	err := provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Core", Interval: model.BillingIntervalMonth, Currency: "eur", ProrationBehavior: model.ProrationBehaviorCreateProrations})
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
+	assert.EqualError(t, err, "subscription 'sub_123' is billed in another currency than 'eur'")
+	mockAPI.AssertExpectations(t)
+}
+
+// TestChangePlanUnknownPlan checks that an unknown plan never reaches the api
+func TestChangePlanUnknownPlan(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	err := provider.ChangePlan(ctx, "sub_123", model.PlanChange{Plan: "Enterprise", Interval: model.BillingIntervalMonth, ProrationBehavior: model.ProrationBehaviorNone})
+
+	assert.Error(t, err)
+	mockAPI.AssertNotCalled(t, "ChangeSubscriptionPrice")
+}
+
+// TestChangeQuantity checks that the quantity is passed on to the api
+func TestChangeQuantity(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ChangeSubscriptionQuantity", ctx, "sub_123", int64(12), "none").
+		Return(nil).
+		Once()
+
+	err := provider.ChangeQuantity(ctx, "sub_123", model.QuantityChange{Quantity: 12, ProrationBehavior: model.ProrationBehaviorNone})
+
+	assert.NoError(t, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestAddAddOn checks that the add-on is added as an item with its price in the currency of the subscription
+func TestAddAddOn(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("AddSubscriptionItem", ctx, "sub_123", "price_storage_eur", int64(2), "ExtraStorage").
+		Return("si_123", nil).
+		Once()
+
+	itemId, err := provider.AddAddOn(ctx, "sub_123", "ExtraStorage", "eur", 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "si_123", itemId)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestAddAddOnUnknown checks that an add-on without a price is rejected
+func TestAddAddOnUnknown(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	_, err := provider.AddAddOn(ctx, "sub_123", "PrioritySupport", "usd", 1)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	// ExtraStorage has no gbp price
+	_, err = provider.AddAddOn(ctx, "sub_123", "ExtraStorage", "gbp", 1)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockAPI.AssertNotCalled(t, "AddSubscriptionItem")
+}
+
+// TestPreviewPlanChange checks that the upcoming invoice is mapped and its prorations are summed up
+func TestPreviewPlanChange(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	invoice := stripeSdk.Invoice{
+		Currency: stripeSdk.CurrencyUSD,
+		Total:    5500,
+		Lines: &stripeSdk.InvoiceLineItemList{
+			Data: []*stripeSdk.InvoiceLineItem{
+				{Description: "Unused time on Core", Amount: -1000, Proration: true, Period: &stripeSdk.Period{Start: 1700000000, End: 1701000000}},
+				{Description: "Remaining time on Premium", Amount: 2500, Proration: true, Period: &stripeSdk.Period{Start: 1700000000, End: 1701000000}},
+				{Description: "Premium", Amount: 4000},
+			},
+		},
+	}
+	mockAPI.
+		On("PreviewSubscriptionPrice", ctx, "sub_123", "price_1QtWcWIGaC2gk9ooNnWu1RJi", "create_prorations").
+		Return(invoice, nil).
+		Once()
+
+	preview, err := provider.PreviewPlanChange(ctx, "sub_123", model.PlanChange{Plan: "Premium", Interval: model.BillingIntervalMonth, ProrationBehavior: model.ProrationBehaviorCreateProrations})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "usd", preview.Currency)
+	assert.Equal(t, int64(5500), preview.Total)
+	assert.Equal(t, int64(1500), preview.ProratedAmount)
+	assert.Len(t, preview.Lines, 3)
+	assert.Equal(t, int64(1700000000), preview.Lines[0].PeriodStart.Unix())
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEvent ensures the adapter maps the verified stripe event to the model
+func TestConstructEvent(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{"id":"evt_123"}`)
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeSdk.Event{ID: "evt_123", Type: "invoice.paid", Created: 1700000000}, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "evt_123", event.EventId)
+	assert.Equal(t, "invoice.paid", event.Type)
+	assert.Equal(t, int64(1700000000), event.CreatedAt.Unix())
+	assert.Equal(t, payload, event.Payload)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventInvalidSignature checks that verification errors are passed through
+func TestConstructEventInvalidSignature(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{"id":"evt_123"}`)
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "").
+		Return(stripeSdk.Event{}, model.NewInvalidWebhookErr("webhook has no Stripe-Signature header")).
+		Once()
+
+	_, err := provider.ConstructEvent(ctx, payload, "")
+
+	assert.IsType(t, model.InvalidWebhookErr{}, err)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventSubscription checks that the subscription object of the event is mapped
+func TestConstructEventSubscription(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_456",
+		"type": "customer.subscription.updated",
+		"created": 1700000000,
+		"data": {"object": {"id": "sub_123", "object": "subscription", "customer": "cus_123", "status": "past_due"}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventSubscriptionUpdated, event.Type)
+	assert.Equal(t, &model.EventSubscription{
+		ExternalSubscriptionId: "sub_123",
+		ExternalCustomerId:     "cus_123",
+		Status:                 model.SubscriptionStatusPastDue,
+	}, event.Subscription)
+	assert.Nil(t, event.Invoice)
+	assert.Nil(t, event.Customer)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventUnreadable checks that a verified event which can't be mapped is still returned
+func TestConstructEventUnreadable(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_456",
+		"type": "customer.subscription.updated",
+		"created": 1700000000,
+		"data": {"object": {"id": "sub_123", "object": "subscription", "customer": "cus_123", "status": "unknown_status"}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.IsType(t, model.UnreadableEventErr{}, err)
+	assert.Equal(t, model.Event{
+		EventId:   "evt_456",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+		Payload:   payload,
+	}, event)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventPausedSubscription checks that a subscription with paused payment collection is mapped as paused
+func TestConstructEventPausedSubscription(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_456",
+		"type": "customer.subscription.updated",
+		"created": 1700000000,
+		"data": {"object": {"id": "sub_123", "object": "subscription", "customer": "cus_123", "status": "active",
+			"pause_collection": {"behavior": "void", "resumes_at": 1710000000}}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPaused, event.Subscription.Status)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventInvoice checks that the invoice object of the event is mapped
+func TestConstructEventInvoice(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_789",
+		"type": "invoice.paid",
+		"created": 1700000000,
+		"data": {"object": {"id": "in_123", "object": "invoice", "customer": "cus_123", "subscription": "sub_123"}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.EventInvoice{
+		ExternalInvoiceId:      "in_123",
+		ExternalSubscriptionId: "sub_123",
+		ExternalCustomerId:     "cus_123",
+	}, event.Invoice)
+	assert.Nil(t, event.Subscription)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestCreateCheckoutSession checks that the plan is passed on with its price
+func TestCreateCheckoutSession(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	customer := model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"}
+	checkout := model.Checkout{Plan: "Growth", Interval: model.BillingIntervalMonth, SuccessUrl: "https://example.com/success", CancelUrl: "https://example.com/cancel"}
+
+	mockAPI.
+		On("CreateCheckoutSession", ctx, customer, "price_1QtWcBIGaC2gk9ookwUgcQPj", "Growth", "month", "https://example.com/success", "https://example.com/cancel").
+		Return(stripeSdk.CheckoutSession{ID: "cs_123", URL: "https://checkout.stripe.com/c/pay/cs_123"}, nil).
+		Once()
+
+	session, err := provider.CreateCheckoutSession(ctx, customer, checkout)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.CheckoutSession{ExternalSessionId: "cs_123", Url: "https://checkout.stripe.com/c/pay/cs_123"}, session)
+	mockAPI.AssertExpectations(t)
+
+	// Unknown plan -> no session
+	_, err = provider.CreateCheckoutSession(ctx, customer, model.Checkout{Plan: "NonExistentPlan", Interval: model.BillingIntervalMonth})
+	assert.Error(t, err)
+}
+
+// TestCreatePortalSession checks that the session is created for the provider customer
+func TestCreatePortalSession(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("CreatePortalSession", ctx, "external-customer-id-1").
+		Return("https://billing.stripe.com/p/session/test_123", nil).
+		Once()
+
+	url, err := provider.CreatePortalSession(ctx, model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://billing.stripe.com/p/session/test_123", url)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestCreateSetupIntent checks that the client secret of the setup intent is returned
+func TestCreateSetupIntent(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("CreateSetupIntent", ctx, "external-customer-id-1").
+		Return(stripeSdk.SetupIntent{ClientSecret: "seti_123_secret_456", Status: stripeSdk.SetupIntentStatusRequiresPaymentMethod}, nil).
+		Once()
+
+	intent, err := provider.CreateSetupIntent(ctx, model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.PaymentConfirmation{
+		Intent:       model.PaymentIntentTypeSetup,
+		ClientSecret: "seti_123_secret_456",
+		Status:       "requires_payment_method",
+	}, intent)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestPaymentMethods checks that cards are mapped and the default one is marked
+func TestPaymentMethods(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	mockAPI.
+		On("ListPaymentMethods", ctx, "external-customer-id-1").
+		Return([]stripeSdk.PaymentMethod{
+			{ID: "pm_1", Type: stripeSdk.PaymentMethodTypeCard, Card: &stripeSdk.PaymentMethodCard{Brand: "visa", Last4: "4242", ExpMonth: 12, ExpYear: 2030}},
+			{ID: "pm_2", Type: stripeSdk.PaymentMethodTypeSEPADebit},
+		}, nil).
+		Once()
+	mockAPI.
+		On("GetDefaultPaymentMethod", ctx, "external-customer-id-1").
+		Return("pm_2", nil).
+		Once()
+
+	methods, err := provider.PaymentMethods(ctx, model.Customer{CustomerId: "customer-id-1", ExternalCustomerId: "external-customer-id-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.PaymentMethod{
+		{ExternalPaymentMethodId: "pm_1", Type: "card", Brand: "visa", Last4: "4242", ExpMonth: 12, ExpYear: 2030},
+		{ExternalPaymentMethodId: "pm_2", Type: "sepa_debit", Default: true},
+	}, methods)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestGetPlanCatalog checks that products are matched to plans by their metadata and the default price of an interval is preferred
+func TestGetPlanCatalog(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	perMonth := &stripeSdk.PriceRecurring{Interval: "month", IntervalCount: 1, UsageType: stripeSdk.PriceRecurringUsageTypeLicensed}
+	perQuarter := &stripeSdk.PriceRecurring{Interval: "month", IntervalCount: 3, UsageType: stripeSdk.PriceRecurringUsageTypeLicensed}
+	perYear := &stripeSdk.PriceRecurring{Interval: "year", IntervalCount: 1, UsageType: stripeSdk.PriceRecurringUsageTypeLicensed}
+	mockAPI.
+		On("ListProducts", ctx).
+		Return([]stripeSdk.Product{
+			{ID: "prod_core", Name: "Core", Metadata: map[string]string{"plan": "Core"}, DefaultPrice: &stripeSdk.Price{ID: "price_core_default", Currency: "usd"}},
+			{ID: "prod_growth", Name: "Growth", Metadata: map[string]string{"plan": "Growth"}},
+			{ID: "prod_storage", Name: "Extra storage"},
+		}, nil).
+		Once()
+	mockAPI.
+		On("ListPrices", ctx).
+		Return([]stripeSdk.Price{
+			{ID: "price_core_new", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "usd", UnitAmount: 1200, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+			{ID: "price_core_default", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "usd", UnitAmount: 900, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+			{ID: "price_core_gbp", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "gbp", UnitAmount: 800, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+			{ID: "price_core_eur", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "eur", UnitAmount: 850, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+			{ID: "price_core_yearly_eur", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "eur", UnitAmount: 8500, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perYear},
+			{ID: "price_core_yearly", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "usd", UnitAmount: 9000, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perYear},
+			{ID: "price_core_quarterly", Active: true, Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "usd", UnitAmount: 2500, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perQuarter},
+			{ID: "price_core_old", Product: &stripeSdk.Product{ID: "prod_core"}, Currency: "usd", UnitAmount: 800, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+			{ID: "price_growth_weekly", Active: true, Product: &stripeSdk.Product{ID: "prod_growth"}, Currency: "usd", UnitAmount: 700, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: &stripeSdk.PriceRecurring{Interval: "week", IntervalCount: 1, UsageType: stripeSdk.PriceRecurringUsageTypeLicensed}},
+			{ID: "price_storage", Active: true, Product: &stripeSdk.Product{ID: "prod_storage"}, Currency: "usd", UnitAmount: 200, BillingScheme: stripeSdk.PriceBillingSchemePerUnit, Recurring: perMonth},
+		}, nil).
+		Once()
+
+	catalog, err := provider.GetPlanCatalog(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.ProviderCatalog{
+		Environment: "test",
+		Plans: []model.ProviderPlan{
+			{PlanId: "Core", Name: "Core", Prices: []model.ProviderPrice{
+				// The currency of the default price comes first
+				{ExternalPriceId: "price_core_default", Interval: model.BillingIntervalMonth, Amount: 900, Currency: "usd"},
+				{ExternalPriceId: "price_core_eur", Interval: model.BillingIntervalMonth, Amount: 850, Currency: "eur"},
+				{ExternalPriceId: "price_core_gbp", Interval: model.BillingIntervalMonth, Amount: 800, Currency: "gbp"},
+				{ExternalPriceId: "price_core_quarterly", Interval: model.BillingIntervalQuarter, Amount: 2500, Currency: "usd"},
+				{ExternalPriceId: "price_core_yearly", Interval: model.BillingIntervalYear, Amount: 9000, Currency: "usd"},
+				{ExternalPriceId: "price_core_yearly_eur", Interval: model.BillingIntervalYear, Amount: 8500, Currency: "eur"},
+			}},
+			// No price a plan can be sold with
+			{PlanId: "Growth", Name: "Growth"},
+		},
+		ArchivedPrices: []string{"price_core_old"},
+	}, catalog)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestConstructEventCheckoutSession checks that the plan is read back from the session metadata
+func TestConstructEventCheckoutSession(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_789",
+		"type": "checkout.session.completed",
+		"created": 1700000000,
+		"data": {"object": {"id": "cs_123", "object": "checkout.session", "mode": "subscription", "customer": "cus_123", "subscription": "sub_123", "currency": "eur", "metadata": {"plan": "Growth", "interval": "year"}}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ConstructEvent", ctx, payload, "t=1,v1=abc").
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ConstructEvent(ctx, payload, "t=1,v1=abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.EventCheckoutSession{
+		ExternalSessionId:      "cs_123",
+		ExternalCustomerId:     "cus_123",
+		ExternalSubscriptionId: "sub_123",
+		Plan:                   "Growth",
+		Interval:               model.BillingIntervalYear,
+		Currency:               "eur",
+	}, event.CheckoutSession)
+	assert.Nil(t, event.Subscription)
+	mockAPI.AssertExpectations(t)
+}
+
+// TestParseEvent checks that a stored payload is mapped like a received one
+func TestParseEvent(t *testing.T) {
+	ctx := context.Background()
+	mockAPI := new(mockApi)
+	provider := stripe.NewAdapter(mockAPI, addOnConfig, plans, planConfig)
+
+	payload := []byte(`{
+		"id": "evt_999",
+		"type": "customer.deleted",
+		"created": 1700000000,
+		"data": {"object": {"id": "cus_123", "object": "customer"}}
+	}`)
+	var stripeEvent stripeSdk.Event
+	assert.NoError(t, json.Unmarshal(payload, &stripeEvent))
+
+	mockAPI.
+		On("ParseEvent", ctx, payload).
+		Return(stripeEvent, nil).
+		Once()
+
+	event, err := provider.ParseEvent(ctx, payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "evt_999", event.EventId)
+	assert.Equal(t, &model.EventCustomer{ExternalCustomerId: "cus_123"}, event.Customer)
+	assert.Equal(t, payload, event.Payload)
 	mockAPI.AssertExpectations(t)
 }
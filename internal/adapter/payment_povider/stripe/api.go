@@ -2,24 +2,61 @@ package stripe
 
 import (
 	"context"
+	"fmt"
 	"github.com/DenisBarabanshchikov/subscription/internal/model"
 	"github.com/stripe/stripe-go/v74"
 	"github.com/stripe/stripe-go/v74/client"
+	"time"
 )
 
 type Api interface {
 	CreateCustomer(ctx context.Context, email string) (string, error)
-	SubscribeCustomer(ctx context.Context, customer model.Customer, price string) (string, error)
+	SubscribeCustomer(ctx context.Context, customer model.Customer, price string, quantity, trialDays int64, trialEndBehavior string) (stripe.Subscription, error)
 	GetSubscriptionStatus(_ context.Context, subscriptionId string) (string, error)
+	GetSubscriptionCurrency(ctx context.Context, subscriptionId string) (string, error)
+	CancelSubscription(ctx context.Context, subscriptionId, reason string) (string, error)
+	CancelSubscriptionAtPeriodEnd(ctx context.Context, subscriptionId, reason string) (string, error)
+	PauseSubscription(ctx context.Context, subscriptionId, behavior string, resumesAt *time.Time) (string, error)
+	ResumeSubscription(ctx context.Context, subscriptionId string) (string, error)
+	ChangeSubscriptionPrice(ctx context.Context, subscriptionId, price, prorationBehavior string) error
+	ChangeSubscriptionQuantity(ctx context.Context, subscriptionId string, quantity int64, prorationBehavior string) error
+	AddSubscriptionItem(ctx context.Context, subscriptionId, price string, quantity int64, addOn string) (string, error)
+	DeleteSubscriptionItem(ctx context.Context, itemId string) error
+	PreviewSubscriptionPrice(ctx context.Context, subscriptionId, price, prorationBehavior string) (stripe.Invoice, error)
+	CreateCheckoutSession(ctx context.Context, customer model.Customer, price, plan, interval, successUrl, cancelUrl string) (stripe.CheckoutSession, error)
+	CreatePortalSession(ctx context.Context, externalCustomerId string) (string, error)
+	CreateSetupIntent(ctx context.Context, externalCustomerId string) (stripe.SetupIntent, error)
+	ListPaymentMethods(ctx context.Context, externalCustomerId string) ([]stripe.PaymentMethod, error)
+	GetDefaultPaymentMethod(ctx context.Context, externalCustomerId string) (string, error)
+	SetDefaultPaymentMethod(ctx context.Context, externalCustomerId, paymentMethodId string) error
+	DetachPaymentMethod(ctx context.Context, paymentMethodId string) error
+	ListProducts(ctx context.Context) ([]stripe.Product, error)
+	ListPrices(ctx context.Context) ([]stripe.Price, error)
+	ConstructEvent(ctx context.Context, payload []byte, signature string) (stripe.Event, error)
+	ParseEvent(ctx context.Context, payload []byte) (stripe.Event, error)
 }
 
+// addOnMetadataKey marks the items of add-ons, the item without it is the one of the plan
+const addOnMetadataKey = "add_on"
+
+// planMetadataKey keeps the plan of a checkout session until the session is completed, on a
+// product it names the catalog plan the product is sold as
+const planMetadataKey = "plan"
+
+// intervalMetadataKey keeps the billing interval of a checkout session until the session is completed
+const intervalMetadataKey = "interval"
+
 type api struct {
-	client *client.API
+	client        *client.API
+	webhookConfig WebhookConfig
+	portalConfig  PortalConfig
 }
 
-func NewApi(client *client.API) Api {
+func NewApi(client *client.API, webhookConfig WebhookConfig, portalConfig PortalConfig) Api {
 	return &api{
-		client: client,
+		client:        client,
+		webhookConfig: webhookConfig,
+		portalConfig:  portalConfig,
 	}
 }
 
@@ -34,22 +71,37 @@ func (a *api) CreateCustomer(_ context.Context, email string) (string, error) {
 	return customer.ID, nil
 }
 
-func (a *api) SubscribeCustomer(_ context.Context, customer model.Customer, price string) (string, error) {
+// SubscribeCustomer creates the subscription, a trial is only requested for positive trialDays
+func (a *api) SubscribeCustomer(_ context.Context, customer model.Customer, price string, quantity, trialDays int64, trialEndBehavior string) (stripe.Subscription, error) {
 	subParams := &stripe.SubscriptionParams{
 		Customer: stripe.String(customer.ExternalCustomerId),
 		Items: []*stripe.SubscriptionItemsParams{
 			{
-				Price: stripe.String(price),
+				Price:    stripe.String(price),
+				Quantity: stripe.Int64(quantity),
 			},
 		},
 		PaymentBehavior: stripe.String("default_incomplete"),
 	}
+	// The client confirms the first payment, or sets up a payment method during a trial
+	subParams.AddExpand("latest_invoice.payment_intent")
+	subParams.AddExpand("pending_setup_intent")
+	if trialDays > 0 {
+		subParams.TrialPeriodDays = stripe.Int64(trialDays)
+	}
+	if trialEndBehavior != "" {
+		subParams.TrialSettings = &stripe.SubscriptionTrialSettingsParams{
+			EndBehavior: &stripe.SubscriptionTrialSettingsEndBehaviorParams{
+				MissingPaymentMethod: stripe.String(trialEndBehavior),
+			},
+		}
+	}
 	subscription, err := a.client.Subscriptions.New(subParams)
 	if err != nil {
-		return "", err
+		return stripe.Subscription{}, err
 	}
 
-	return subscription.ID, nil
+	return *subscription, nil
 }
 
 func (a *api) GetSubscriptionStatus(_ context.Context, subscriptionId string) (string, error) {
@@ -58,5 +110,218 @@ func (a *api) GetSubscriptionStatus(_ context.Context, subscriptionId string) (s
 		return "", err
 	}
 
-	return string(subscription.Status), nil
+	return string(effectiveStatus(subscription)), nil
+}
+
+func (a *api) GetSubscriptionCurrency(_ context.Context, subscriptionId string) (string, error) {
+	subscription, err := a.client.Subscriptions.Get(subscriptionId, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(subscription.Currency), nil
+}
+
+// CancelSubscription cancels the subscription right away and returns its new status
+func (a *api) CancelSubscription(_ context.Context, subscriptionId, reason string) (string, error) {
+	params := &stripe.SubscriptionCancelParams{}
+	if reason != "" {
+		params.CancellationDetails = &stripe.SubscriptionCancelCancellationDetailsParams{
+			Comment: stripe.String(reason),
+		}
+	}
+	subscription, err := a.client.Subscriptions.Cancel(subscriptionId, params)
+	if err != nil {
+		return "", err
+	}
+
+	return string(effectiveStatus(subscription)), nil
+}
+
+// CancelSubscriptionAtPeriodEnd lets the subscription end with the current period and returns its status
+func (a *api) CancelSubscriptionAtPeriodEnd(_ context.Context, subscriptionId, reason string) (string, error) {
+	params := &stripe.SubscriptionParams{
+		CancelAtPeriodEnd: stripe.Bool(true),
+	}
+	if reason != "" {
+		params.CancellationDetails = &stripe.SubscriptionCancellationDetailsParams{
+			Comment: stripe.String(reason),
+		}
+	}
+	subscription, err := a.client.Subscriptions.Update(subscriptionId, params)
+	if err != nil {
+		return "", err
+	}
+
+	return string(effectiveStatus(subscription)), nil
+}
+
+// PauseSubscription pauses the payment collection of the subscription and returns its status
+func (a *api) PauseSubscription(_ context.Context, subscriptionId, behavior string, resumesAt *time.Time) (string, error) {
+	pauseCollection := &stripe.SubscriptionPauseCollectionParams{
+		Behavior: stripe.String(behavior),
+	}
+	if resumesAt != nil {
+		pauseCollection.ResumesAt = stripe.Int64(resumesAt.Unix())
+	}
+	subscription, err := a.client.Subscriptions.Update(subscriptionId, &stripe.SubscriptionParams{
+		PauseCollection: pauseCollection,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(effectiveStatus(subscription)), nil
+}
+
+// ResumeSubscription resumes the subscription and returns its status. A subscription paused at the end
+// of its trial is paused by Stripe itself and has to be resumed, otherwise only its payment collection
+// is paused and gets unset.
+func (a *api) ResumeSubscription(_ context.Context, subscriptionId string) (string, error) {
+	subscription, err := a.client.Subscriptions.Get(subscriptionId, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if subscription.Status == stripe.SubscriptionStatusPaused {
+		subscription, err = a.client.Subscriptions.Resume(subscriptionId, &stripe.SubscriptionResumeParams{})
+		if err != nil {
+			return "", err
+		}
+	}
+	if subscription.PauseCollection != nil {
+		params := &stripe.SubscriptionParams{}
+		params.AddExtra("pause_collection", "")
+		subscription, err = a.client.Subscriptions.Update(subscriptionId, params)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return string(effectiveStatus(subscription)), nil
+}
+
+// ChangeSubscriptionPrice swaps the price of the plan item of the subscription
+func (a *api) ChangeSubscriptionPrice(_ context.Context, subscriptionId, price, prorationBehavior string) error {
+	itemId, err := a.getPlanItemId(subscriptionId)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.Subscriptions.Update(subscriptionId, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(itemId),
+				Price: stripe.String(price),
+			},
+		},
+		ProrationBehavior: stripe.String(prorationBehavior),
+	})
+	return err
+}
+
+// ChangeSubscriptionQuantity sets the quantity of the plan item of the subscription
+func (a *api) ChangeSubscriptionQuantity(_ context.Context, subscriptionId string, quantity int64, prorationBehavior string) error {
+	itemId, err := a.getPlanItemId(subscriptionId)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.Subscriptions.Update(subscriptionId, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:       stripe.String(itemId),
+				Quantity: stripe.Int64(quantity),
+			},
+		},
+		ProrationBehavior: stripe.String(prorationBehavior),
+	})
+	return err
+}
+
+// PreviewSubscriptionPrice returns the upcoming invoice of the subscription as if its price was
+// swapped, the subscription itself stays unchanged
+func (a *api) PreviewSubscriptionPrice(_ context.Context, subscriptionId, price, prorationBehavior string) (stripe.Invoice, error) {
+	itemId, err := a.getPlanItemId(subscriptionId)
+	if err != nil {
+		return stripe.Invoice{}, err
+	}
+
+	invoice, err := a.client.Invoices.Upcoming(&stripe.InvoiceUpcomingParams{
+		Subscription: stripe.String(subscriptionId),
+		SubscriptionItems: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(itemId),
+				Price: stripe.String(price),
+			},
+		},
+		SubscriptionProrationBehavior: stripe.String(prorationBehavior),
+	})
+	if err != nil {
+		return stripe.Invoice{}, err
+	}
+
+	return *invoice, nil
+}
+
+// AddSubscriptionItem adds the item of an add-on to the subscription and returns the item id
+func (a *api) AddSubscriptionItem(_ context.Context, subscriptionId, price string, quantity int64, addOn string) (string, error) {
+	item, err := a.client.SubscriptionItems.New(&stripe.SubscriptionItemParams{
+		Subscription: stripe.String(subscriptionId),
+		Price:        stripe.String(price),
+		Quantity:     stripe.Int64(quantity),
+		Params: stripe.Params{
+			Metadata: map[string]string{addOnMetadataKey: addOn},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return item.ID, nil
+}
+
+func (a *api) DeleteSubscriptionItem(_ context.Context, itemId string) error {
+	_, err := a.client.SubscriptionItems.Del(itemId, nil)
+	return err
+}
+
+// CreateCheckoutSession creates a session in subscription mode for a single seat of the price
+func (a *api) CreateCheckoutSession(_ context.Context, customer model.Customer, price, plan, interval, successUrl, cancelUrl string) (stripe.CheckoutSession, error) {
+	params := &stripe.CheckoutSessionParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		Customer:          stripe.String(customer.ExternalCustomerId),
+		ClientReferenceID: stripe.String(customer.CustomerId),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(price),
+				Quantity: stripe.Int64(1),
+			},
+		},
+		SuccessURL: stripe.String(successUrl),
+		CancelURL:  stripe.String(cancelUrl),
+	}
+	params.AddMetadata(planMetadataKey, plan)
+	params.AddMetadata(intervalMetadataKey, interval)
+	session, err := a.client.CheckoutSessions.New(params)
+	if err != nil {
+		return stripe.CheckoutSession{}, err
+	}
+
+	return *session, nil
+}
+
+func (a *api) getPlanItemId(subscriptionId string) (string, error) {
+	subscription, err := a.client.Subscriptions.Get(subscriptionId, nil)
+	if err != nil {
+		return "", err
+	}
+	if subscription.Items != nil {
+		for _, item := range subscription.Items.Data {
+			if item.Metadata[addOnMetadataKey] == "" {
+				return item.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("subscription %s has no plan item", subscriptionId)
 }
@@ -37,7 +37,7 @@ func (s *IntegrationTestSuite) SetupSuite() {
 	sc.Init(secretKey, nil)
 
 	// Create the API adapter
-	s.api = paymentProvider.NewApi(sc)
+	s.api = paymentProvider.NewApi(sc, paymentProvider.WebhookConfig{}, paymentProvider.PortalConfig{})
 }
 
 // TearDownSuite runs after the tests in this suite
@@ -81,12 +81,12 @@ func (s *IntegrationTestSuite) TestSubscribeCustomer() {
 		ExternalCustomerId: s.customerID, // important
 	}
 
-	subID, err := s.api.SubscribeCustomer(ctx, cust, testPrice)
+	sub, err := s.api.SubscribeCustomer(ctx, cust, testPrice, 1, 0, "")
 	require.NoError(s.T(), err)
-	require.NotEmpty(s.T(), subID)
+	require.NotEmpty(s.T(), sub.ID)
 
-	s.subID = subID
-	s.T().Logf("Created subscription: %s", subID)
+	s.subID = sub.ID
+	s.T().Logf("Created subscription: %s", sub.ID)
 }
 
 // TestGetSubscriptionStatus fetches the subscription status and checks if it's "incomplete" by default
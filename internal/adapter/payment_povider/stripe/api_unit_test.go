@@ -0,0 +1,71 @@
+//go:build unit
+
+package stripe_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	stripeSdk "github.com/stripe/stripe-go/v74"
+	"github.com/stripe/stripe-go/v74/client"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
+)
+
+// newStubApi returns an api which sends its Stripe requests to the given responses, keyed by method and
+// path. The requests made are returned in calls.
+func newStubApi(t *testing.T, responses map[string]string, calls *[]string) stripe.Api {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+		*calls = append(*calls, call)
+		response, ok := responses[call]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			response = `{"error":{"type":"invalid_request_error","message":"unexpected request"}}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	backend := stripeSdk.GetBackendWithConfig(stripeSdk.APIBackend, &stripeSdk.BackendConfig{
+		URL:               stripeSdk.String(server.URL),
+		MaxNetworkRetries: stripeSdk.Int64(0),
+		LeveledLogger:     &stripeSdk.LeveledLogger{Level: stripeSdk.LevelNull},
+	})
+	sc := &client.API{}
+	sc.Init("sk_test_123", &stripeSdk.Backends{API: backend})
+	return stripe.NewApi(sc, stripe.WebhookConfig{}, stripe.PortalConfig{})
+}
+
+// TestApiResumeSubscriptionPausedAtTrialEnd resumes a subscription Stripe paused at the end of its trial
+func TestApiResumeSubscriptionPausedAtTrialEnd(t *testing.T) {
+	var calls []string
+	api := newStubApi(t, map[string]string{
+		"GET /v1/subscriptions/sub_123":         `{"id":"sub_123","object":"subscription","status":"paused"}`,
+		"POST /v1/subscriptions/sub_123/resume": `{"id":"sub_123","object":"subscription","status":"active"}`,
+	}, &calls)
+
+	status, err := api.ResumeSubscription(context.Background(), "sub_123")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", status)
+	assert.Equal(t, []string{"GET /v1/subscriptions/sub_123", "POST /v1/subscriptions/sub_123/resume"}, calls)
+}
+
+// TestApiResumeSubscriptionPausedCollection unsets the paused payment collection without resuming
+func TestApiResumeSubscriptionPausedCollection(t *testing.T) {
+	var calls []string
+	api := newStubApi(t, map[string]string{
+		"GET /v1/subscriptions/sub_123":  `{"id":"sub_123","object":"subscription","status":"active","pause_collection":{"behavior":"void"}}`,
+		"POST /v1/subscriptions/sub_123": `{"id":"sub_123","object":"subscription","status":"active"}`,
+	}, &calls)
+
+	status, err := api.ResumeSubscription(context.Background(), "sub_123")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", status)
+	assert.Equal(t, []string{"GET /v1/subscriptions/sub_123", "POST /v1/subscriptions/sub_123"}, calls)
+}
@@ -0,0 +1,352 @@
+package stripe
+
+import (
+	"encoding/json"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/pkg/errors"
+	"github.com/stripe/stripe-go/v74"
+	"sort"
+	"time"
+)
+
+// mapToVerifiedEventModel keeps an event whose object can't be mapped, so it can still be recorded. It
+// is returned without its object and with a model.UnreadableEventErr.
+func mapToVerifiedEventModel(event stripe.Event, payload []byte) (model.Event, error) {
+	res, err := mapToEventModel(event, payload)
+	if err != nil {
+		return model.Event{
+			EventId:   event.ID,
+			Type:      event.Type,
+			CreatedAt: time.Unix(event.Created, 0).UTC(),
+			Payload:   payload,
+		}, model.NewUnreadableEventErr(event.ID, err.Error())
+	}
+	return res, nil
+}
+
+func mapToEventModel(event stripe.Event, payload []byte) (model.Event, error) {
+	res := model.Event{
+		EventId:   event.ID,
+		Type:      event.Type,
+		CreatedAt: time.Unix(event.Created, 0).UTC(),
+		Payload:   payload,
+	}
+	if event.Data == nil {
+		return res, nil
+	}
+
+	var err error
+	switch event.Data.Object["object"] {
+	case "subscription":
+		var subscription stripe.Subscription
+		if err = json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+			return model.Event{}, errors.Wrapf(err, "failed to unmarshal subscription of event '%s'", event.ID)
+		}
+		res.Subscription, err = mapToEventSubscription(subscription)
+		if err != nil {
+			return model.Event{}, errors.Wrapf(err, "failed to map subscription of event '%s'", event.ID)
+		}
+	case "invoice":
+		var invoice stripe.Invoice
+		if err = json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+			return model.Event{}, errors.Wrapf(err, "failed to unmarshal invoice of event '%s'", event.ID)
+		}
+		res.Invoice = mapToEventInvoice(invoice)
+	case "customer":
+		var customer stripe.Customer
+		if err = json.Unmarshal(event.Data.Raw, &customer); err != nil {
+			return model.Event{}, errors.Wrapf(err, "failed to unmarshal customer of event '%s'", event.ID)
+		}
+		res.Customer = &model.EventCustomer{ExternalCustomerId: customer.ID}
+	case "checkout.session":
+		var session stripe.CheckoutSession
+		if err = json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return model.Event{}, errors.Wrapf(err, "failed to unmarshal checkout session of event '%s'", event.ID)
+		}
+		res.CheckoutSession = mapToEventCheckoutSession(session)
+	}
+
+	return res, nil
+}
+
+func mapToEventSubscription(subscription stripe.Subscription) (*model.EventSubscription, error) {
+	status, err := mapToSubscriptionStatus(effectiveStatus(&subscription))
+	if err != nil {
+		return nil, err
+	}
+	res := &model.EventSubscription{
+		ExternalSubscriptionId: subscription.ID,
+		Status:                 status,
+		CancelAtPeriodEnd:      subscription.CancelAtPeriodEnd,
+	}
+	if subscription.Customer != nil {
+		res.ExternalCustomerId = subscription.Customer.ID
+	}
+	return res, nil
+}
+
+func mapToEventCheckoutSession(session stripe.CheckoutSession) *model.EventCheckoutSession {
+	res := &model.EventCheckoutSession{
+		ExternalSessionId: session.ID,
+		Plan:              session.Metadata[planMetadataKey],
+		Interval:          model.BillingInterval(session.Metadata[intervalMetadataKey]),
+		Currency:          string(session.Currency),
+	}
+	if session.Customer != nil {
+		res.ExternalCustomerId = session.Customer.ID
+	}
+	if session.Subscription != nil {
+		res.ExternalSubscriptionId = session.Subscription.ID
+	}
+	return res
+}
+
+func mapToProviderSubscription(subscription stripe.Subscription) (model.ProviderSubscription, error) {
+	status, err := mapToSubscriptionStatus(effectiveStatus(&subscription))
+	if err != nil {
+		return model.ProviderSubscription{}, err
+	}
+	res := model.ProviderSubscription{
+		ExternalSubscriptionId: subscription.ID,
+		Status:                 status,
+		Currency:               string(subscription.Currency),
+	}
+	if subscription.TrialEnd > 0 {
+		trialEnd := time.Unix(subscription.TrialEnd, 0).UTC()
+		res.TrialEnd = &trialEnd
+	}
+	res.Payment = mapToPaymentConfirmation(subscription)
+	return res, nil
+}
+
+// mapToPaymentConfirmation prefers the payment intent of the first invoice, a trial has none
+// to pay and brings a pending setup intent instead
+func mapToPaymentConfirmation(subscription stripe.Subscription) *model.PaymentConfirmation {
+	if subscription.LatestInvoice != nil && subscription.LatestInvoice.PaymentIntent != nil {
+		intent := subscription.LatestInvoice.PaymentIntent
+		res := &model.PaymentConfirmation{
+			Intent:       model.PaymentIntentTypePayment,
+			ClientSecret: intent.ClientSecret,
+			Status:       string(intent.Status),
+		}
+		if intent.NextAction != nil {
+			res.NextAction = string(intent.NextAction.Type)
+			if intent.NextAction.RedirectToURL != nil {
+				res.RedirectUrl = intent.NextAction.RedirectToURL.URL
+			}
+		}
+		return res
+	}
+	if subscription.PendingSetupIntent != nil {
+		return mapToSetupConfirmation(subscription.PendingSetupIntent)
+	}
+	return nil
+}
+
+func mapToSetupConfirmation(intent *stripe.SetupIntent) *model.PaymentConfirmation {
+	res := &model.PaymentConfirmation{
+		Intent:       model.PaymentIntentTypeSetup,
+		ClientSecret: intent.ClientSecret,
+		Status:       string(intent.Status),
+	}
+	if intent.NextAction != nil {
+		res.NextAction = string(intent.NextAction.Type)
+		if intent.NextAction.RedirectToURL != nil {
+			res.RedirectUrl = intent.NextAction.RedirectToURL.URL
+		}
+	}
+	return res
+}
+
+func mapToPaymentMethodsModel(methods []stripe.PaymentMethod, defaultId string) []model.PaymentMethod {
+	res := make([]model.PaymentMethod, 0, len(methods))
+	for _, method := range methods {
+		paymentMethod := model.PaymentMethod{
+			ExternalPaymentMethodId: method.ID,
+			Type:                    string(method.Type),
+			Default:                 method.ID == defaultId,
+		}
+		if method.Card != nil {
+			paymentMethod.Brand = string(method.Card.Brand)
+			paymentMethod.Last4 = method.Card.Last4
+			paymentMethod.ExpMonth = int(method.Card.ExpMonth)
+			paymentMethod.ExpYear = int(method.Card.ExpYear)
+		}
+		res = append(res, paymentMethod)
+	}
+	return res
+}
+
+// effectiveStatus reports a subscription with paused payment collection as paused, stripe itself
+// keeps its status unchanged
+func effectiveStatus(subscription *stripe.Subscription) stripe.SubscriptionStatus {
+	if subscription.PauseCollection == nil {
+		return subscription.Status
+	}
+	switch subscription.Status {
+	case stripe.SubscriptionStatusActive, stripe.SubscriptionStatusTrialing, stripe.SubscriptionStatusPastDue:
+		return stripe.SubscriptionStatusPaused
+	default:
+		return subscription.Status
+	}
+}
+
+func mapToSubscriptionStatus(status stripe.SubscriptionStatus) (model.SubscriptionStatus, error) {
+	switch status {
+	case stripe.SubscriptionStatusIncomplete:
+		return model.SubscriptionStatusIncomplete, nil
+	case stripe.SubscriptionStatusIncompleteExpired:
+		return model.SubscriptionStatusIncompleteExpired, nil
+	case stripe.SubscriptionStatusTrialing:
+		return model.SubscriptionStatusTrialing, nil
+	case stripe.SubscriptionStatusActive:
+		return model.SubscriptionStatusActive, nil
+	case stripe.SubscriptionStatusPastDue:
+		return model.SubscriptionStatusPastDue, nil
+	case stripe.SubscriptionStatusUnpaid:
+		return model.SubscriptionStatusUnpaid, nil
+	case stripe.SubscriptionStatusPaused:
+		return model.SubscriptionStatusPaused, nil
+	case stripe.SubscriptionStatusCanceled:
+		return model.SubscriptionStatusCanceled, nil
+	default:
+		return "", errors.Errorf("unknown stripe subscription status '%s'", status)
+	}
+}
+
+func mapToEventInvoice(invoice stripe.Invoice) *model.EventInvoice {
+	res := &model.EventInvoice{
+		ExternalInvoiceId: invoice.ID,
+	}
+	if invoice.Subscription != nil {
+		res.ExternalSubscriptionId = invoice.Subscription.ID
+	}
+	if invoice.Customer != nil {
+		res.ExternalCustomerId = invoice.Customer.ID
+	}
+	return res
+}
+
+func mapToInvoicePreview(invoice stripe.Invoice) model.InvoicePreview {
+	res := model.InvoicePreview{
+		Currency: string(invoice.Currency),
+		Total:    invoice.Total,
+	}
+	if invoice.Lines == nil {
+		return res
+	}
+	for _, line := range invoice.Lines.Data {
+		item := model.InvoiceLine{
+			Description: line.Description,
+			Amount:      line.Amount,
+			Proration:   line.Proration,
+		}
+		if line.Period != nil {
+			item.PeriodStart = time.Unix(line.Period.Start, 0).UTC()
+			item.PeriodEnd = time.Unix(line.Period.End, 0).UTC()
+		}
+		if line.Proration {
+			res.ProratedAmount += line.Amount
+		}
+		res.Lines = append(res.Lines, item)
+	}
+	return res
+}
+
+// mapToProviderCatalog maps the products with a plan in their metadata to plans. Per billing interval and
+// currency the default price of a product is preferred, otherwise its newest price a plan can be sold with.
+// The prices in the currency of the default price come first, it is the main currency of the plan.
+func mapToProviderCatalog(products []stripe.Product, prices []stripe.Price, environment string) model.ProviderCatalog {
+	res := model.ProviderCatalog{Environment: environment}
+
+	pricesByProduct := make(map[string][]stripe.Price)
+	for _, price := range prices {
+		if !price.Active {
+			res.ArchivedPrices = append(res.ArchivedPrices, price.ID)
+			continue
+		}
+		if price.Product != nil && isPlanPrice(price) {
+			pricesByProduct[price.Product.ID] = append(pricesByProduct[price.Product.ID], price)
+		}
+	}
+
+	for _, product := range products {
+		planId := product.Metadata[planMetadataKey]
+		if planId == "" {
+			continue
+		}
+		isDefault := func(price stripe.Price) bool {
+			return product.DefaultPrice != nil && price.ID == product.DefaultPrice.ID
+		}
+		byKey := make(map[priceKey]stripe.Price)
+		for _, price := range pricesByProduct[product.ID] {
+			key := priceKey{interval: mapToBillingInterval(price.Recurring), currency: string(price.Currency)}
+			current, ok := byKey[key]
+			if !ok || (isDefault(price) && !isDefault(current)) {
+				byKey[key] = price
+			}
+		}
+
+		plan := model.ProviderPlan{
+			PlanId: planId,
+			Name:   product.Name,
+		}
+		for key, price := range byKey {
+			plan.Prices = append(plan.Prices, model.ProviderPrice{
+				ExternalPriceId: price.ID,
+				Interval:        key.interval,
+				Amount:          price.UnitAmount,
+				Currency:        key.currency,
+			})
+		}
+		var mainCurrency string
+		if product.DefaultPrice != nil {
+			mainCurrency = string(product.DefaultPrice.Currency)
+		}
+		sort.Slice(plan.Prices, func(i, j int) bool {
+			a, b := plan.Prices[i], plan.Prices[j]
+			if a.Interval != b.Interval {
+				return a.Interval.Months() < b.Interval.Months()
+			}
+			if (a.Currency == mainCurrency) != (b.Currency == mainCurrency) {
+				return a.Currency == mainCurrency
+			}
+			return a.Currency < b.Currency
+		})
+		res.Plans = append(res.Plans, plan)
+	}
+
+	return res
+}
+
+// priceKey identifies a price of a plan, a plan is sold with one price per billing interval and currency
+type priceKey struct {
+	interval model.BillingInterval
+	currency string
+}
+
+// isPlanPrice reports whether a plan can be sold with the price, which bills a fixed amount per seat
+// every billing interval
+func isPlanPrice(price stripe.Price) bool {
+	if price.Recurring == nil || price.Recurring.UsageType != stripe.PriceRecurringUsageTypeLicensed {
+		return false
+	}
+	if price.BillingScheme != stripe.PriceBillingSchemePerUnit {
+		return false
+	}
+	return mapToBillingInterval(price.Recurring).IsValid()
+}
+
+// mapToBillingInterval returns an empty interval for a recurrence which isn't a billing interval,
+// a quarter is billed every 3 months
+func mapToBillingInterval(recurring *stripe.PriceRecurring) model.BillingInterval {
+	switch {
+	case recurring.Interval == stripe.PriceRecurringIntervalMonth && recurring.IntervalCount == 1:
+		return model.BillingIntervalMonth
+	case recurring.Interval == stripe.PriceRecurringIntervalMonth && recurring.IntervalCount == 3:
+		return model.BillingIntervalQuarter
+	case recurring.Interval == stripe.PriceRecurringIntervalYear && recurring.IntervalCount == 1:
+		return model.BillingIntervalYear
+	}
+	return ""
+}
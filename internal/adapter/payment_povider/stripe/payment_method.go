@@ -0,0 +1,65 @@
+package stripe
+
+import (
+	"context"
+	"github.com/stripe/stripe-go/v74"
+)
+
+// CreateSetupIntent saves the payment method the customer confirms for payments without them being present
+func (a *api) CreateSetupIntent(_ context.Context, externalCustomerId string) (stripe.SetupIntent, error) {
+	intent, err := a.client.SetupIntents.New(&stripe.SetupIntentParams{
+		Customer: stripe.String(externalCustomerId),
+		Usage:    stripe.String(string(stripe.SetupIntentUsageOffSession)),
+		AutomaticPaymentMethods: &stripe.SetupIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+	})
+	if err != nil {
+		return stripe.SetupIntent{}, err
+	}
+
+	return *intent, nil
+}
+
+func (a *api) ListPaymentMethods(_ context.Context, externalCustomerId string) ([]stripe.PaymentMethod, error) {
+	iter := a.client.PaymentMethods.List(&stripe.PaymentMethodListParams{
+		Customer: stripe.String(externalCustomerId),
+	})
+	var res []stripe.PaymentMethod
+	for iter.Next() {
+		res = append(res, *iter.PaymentMethod())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetDefaultPaymentMethod returns an empty id if the customer has no default payment method
+func (a *api) GetDefaultPaymentMethod(_ context.Context, externalCustomerId string) (string, error) {
+	customer, err := a.client.Customers.Get(externalCustomerId, nil)
+	if err != nil {
+		return "", err
+	}
+	if customer.InvoiceSettings == nil || customer.InvoiceSettings.DefaultPaymentMethod == nil {
+		return "", nil
+	}
+
+	return customer.InvoiceSettings.DefaultPaymentMethod.ID, nil
+}
+
+// SetDefaultPaymentMethod sets the method used for the invoices of all subscriptions without their own
+func (a *api) SetDefaultPaymentMethod(_ context.Context, externalCustomerId, paymentMethodId string) error {
+	_, err := a.client.Customers.Update(externalCustomerId, &stripe.CustomerParams{
+		InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+			DefaultPaymentMethod: stripe.String(paymentMethodId),
+		},
+	})
+	return err
+}
+
+func (a *api) DetachPaymentMethod(_ context.Context, paymentMethodId string) error {
+	_, err := a.client.PaymentMethods.Detach(paymentMethodId, nil)
+	return err
+}
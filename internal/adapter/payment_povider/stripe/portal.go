@@ -0,0 +1,32 @@
+package stripe
+
+import (
+	"context"
+	"github.com/stripe/stripe-go/v74"
+)
+
+// PortalConfig is used for every billing portal session. Empty values fall back to the
+// default portal configuration set up in the Stripe dashboard.
+type PortalConfig struct {
+	ReturnUrl       string
+	ConfigurationId string
+}
+
+// CreatePortalSession returns the url of the billing portal of the customer
+func (a *api) CreatePortalSession(_ context.Context, externalCustomerId string) (string, error) {
+	params := &stripe.BillingPortalSessionParams{
+		Customer: stripe.String(externalCustomerId),
+	}
+	if a.portalConfig.ReturnUrl != "" {
+		params.ReturnURL = stripe.String(a.portalConfig.ReturnUrl)
+	}
+	if a.portalConfig.ConfigurationId != "" {
+		params.Configuration = stripe.String(a.portalConfig.ConfigurationId)
+	}
+	session, err := a.client.BillingPortalSessions.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return session.URL, nil
+}
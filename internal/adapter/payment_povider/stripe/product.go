@@ -0,0 +1,40 @@
+package stripe
+
+import (
+	"context"
+	"github.com/stripe/stripe-go/v74"
+)
+
+// ListProducts returns the active products, their default prices are expanded
+func (a *api) ListProducts(_ context.Context) ([]stripe.Product, error) {
+	params := &stripe.ProductListParams{
+		Active: stripe.Bool(true),
+	}
+	params.AddExpand("data.default_price")
+	iter := a.client.Products.List(params)
+	var res []stripe.Product
+	for iter.Next() {
+		res = append(res, *iter.Product())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListPrices returns the recurring prices of all products, archived ones included
+func (a *api) ListPrices(_ context.Context) ([]stripe.Price, error) {
+	iter := a.client.Prices.List(&stripe.PriceListParams{
+		Type: stripe.String(string(stripe.PriceTypeRecurring)),
+	})
+	var res []stripe.Price
+	for iter.Next() {
+		res = append(res, *iter.Price())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
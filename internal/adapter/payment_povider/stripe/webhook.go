@@ -0,0 +1,39 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/pkg/errors"
+	"github.com/stripe/stripe-go/v74"
+	"github.com/stripe/stripe-go/v74/webhook"
+	"time"
+)
+
+type WebhookConfig struct {
+	Secret    string
+	Tolerance time.Duration
+}
+
+func (a *api) ConstructEvent(_ context.Context, payload []byte, signature string) (stripe.Event, error) {
+	event, err := webhook.ConstructEventWithOptions(payload, signature, a.webhookConfig.Secret, webhook.ConstructEventOptions{
+		Tolerance: a.webhookConfig.Tolerance,
+		// Only a few fields of the event are read, so the endpoint may use a different API version
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return stripe.Event{}, model.NewInvalidWebhookErr(err.Error())
+	}
+
+	return event, nil
+}
+
+// ParseEvent reads an event which was already verified when it was received
+func (a *api) ParseEvent(_ context.Context, payload []byte) (stripe.Event, error) {
+	var event stripe.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return stripe.Event{}, errors.Wrap(err, "failed to parse stripe event")
+	}
+
+	return event, nil
+}
@@ -0,0 +1,80 @@
+//go:build unit
+
+package stripe_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/v74/webhook"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/payment_povider/stripe"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+const testWebhookSecret = "whsec_test"
+
+var testPayload = []byte(`{"id":"evt_123","object":"event","type":"invoice.paid","created":1700000000,"data":{"object":{}}}`)
+
+func newWebhookApi() stripe.Api {
+	return stripe.NewApi(nil, stripe.WebhookConfig{
+		Secret:    testWebhookSecret,
+		Tolerance: 5 * time.Minute,
+	}, stripe.PortalConfig{})
+}
+
+func signPayload(payload []byte, secret string, timestamp time.Time) string {
+	return webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: timestamp,
+	}).Header
+}
+
+// TestApiConstructEvent checks that a correctly signed payload is accepted
+func TestApiConstructEvent(t *testing.T) {
+	api := newWebhookApi()
+
+	event, err := api.ConstructEvent(context.Background(), testPayload, signPayload(testPayload, testWebhookSecret, time.Now()))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "evt_123", event.ID)
+	assert.Equal(t, "invoice.paid", event.Type)
+}
+
+// TestApiConstructEventRejected checks missing, forged and replayed signatures
+func TestApiConstructEventRejected(t *testing.T) {
+	api := newWebhookApi()
+
+	tests := map[string]string{
+		"missing signature": "",
+		"malformed header":  "not-a-signature",
+		"wrong secret":      signPayload(testPayload, "whsec_other", time.Now()),
+		"replayed payload":  signPayload(testPayload, testWebhookSecret, time.Now().Add(-time.Hour)),
+	}
+	for name, signature := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := api.ConstructEvent(context.Background(), testPayload, signature)
+			assert.IsType(t, model.InvalidWebhookErr{}, err)
+		})
+	}
+
+	// Tampered payload signed for the original body
+	tampered := []byte(`{"id":"evt_456","object":"event","type":"invoice.paid","created":1700000000,"data":{"object":{}}}`)
+	_, err := api.ConstructEvent(context.Background(), tampered, signPayload(testPayload, testWebhookSecret, time.Now()))
+	assert.IsType(t, model.InvalidWebhookErr{}, err)
+}
+
+// TestApiParseEvent checks that a stored payload is read without a signature
+func TestApiParseEvent(t *testing.T) {
+	api := newWebhookApi()
+
+	event, err := api.ParseEvent(context.Background(), testPayload)
+	assert.NoError(t, err)
+	assert.Equal(t, "evt_123", event.ID)
+
+	_, err = api.ParseEvent(context.Background(), []byte("not json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/port"
+)
+
+type adapter struct {
+	repository Repository
+}
+
+func NewAdapter(repository Repository) port.Plan {
+	return &adapter{
+		repository: repository,
+	}
+}
+
+func (a *adapter) GetPlan(ctx context.Context, planId string) (*model.Plan, error) {
+	plan, err := a.repository.GetPlan(ctx, planId)
+	if err != nil {
+		return nil, err
+	}
+	return mapToPlanModelPtr(plan), nil
+}
+
+func (a *adapter) GetPlans(ctx context.Context) ([]model.Plan, error) {
+	plans, err := a.repository.GetPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapToPlansModel(plans), nil
+}
+
+func (a *adapter) CreatePlan(ctx context.Context, plan model.Plan) error {
+	return a.repository.CreatePlan(ctx, mapToPlanEntity(plan))
+}
+
+func (a *adapter) PutPlan(ctx context.Context, plan model.Plan) error {
+	return a.repository.PutPlan(ctx, mapToPlanEntity(plan))
+}
@@ -0,0 +1,191 @@
+//go:build unit
+
+package plan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/plan"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+// mockRepository is a mock of the plan.Repository interface
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) GetPlan(ctx context.Context, planId string) (*plan.Plan, error) {
+	args := m.Called(ctx, planId)
+	if p, ok := args.Get(0).(*plan.Plan); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) GetPlans(ctx context.Context) ([]plan.Plan, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]plan.Plan), args.Error(1)
+}
+
+func (m *mockRepository) CreatePlan(ctx context.Context, entity plan.Plan) error {
+	args := m.Called(ctx, entity)
+	return args.Error(0)
+}
+
+func (m *mockRepository) PutPlan(ctx context.Context, entity plan.Plan) error {
+	args := m.Called(ctx, entity)
+	return args.Error(0)
+}
+
+// TestGetPlan checks that the entity is mapped to the model
+func TestGetPlan(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("GetPlan", ctx, "Core").
+		Return(&plan.Plan{
+			PlanId: "Core",
+			Name:   "Core",
+			Prices: []plan.Price{
+				{Interval: "month", Amount: 900, Currency: "usd", PriceIds: map[string]string{"test": "price_test", "live": "price_live"}},
+				{Interval: "year", Amount: 9000, Currency: "usd", PriceIds: map[string]string{"test": "price_test_yearly"}},
+			},
+			Features: []string{"5 projects"},
+		}, nil).
+		Once()
+
+	res, err := adapter.GetPlan(ctx, "Core")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Plan{
+		PlanId: "Core",
+		Name:   "Core",
+		Prices: []model.PlanPrice{
+			{Interval: model.BillingIntervalMonth, Amount: 900, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_test", "live": "price_live"}},
+			{Interval: model.BillingIntervalYear, Amount: 9000, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_test_yearly"}},
+		},
+		Features: []string{"5 projects"},
+	}, res)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetPlan_LegacyPrice checks that the single price of a plan stored before billing intervals is read
+func TestGetPlan_LegacyPrice(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("GetPlan", ctx, "Core").
+		Return(&plan.Plan{
+			PlanId:         "Core",
+			Name:           "Core",
+			LegacyPriceIds: map[string]string{"test": "price_test"},
+			LegacyAmount:   900,
+			LegacyCurrency: "usd",
+		}, nil).
+		Once()
+
+	res, err := adapter.GetPlan(ctx, "Core")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.PlanPrice{
+		{Interval: model.BillingIntervalMonth, Amount: 900, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_test"}},
+	}, res.Prices)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetPlan_NotFound checks that a missing plan is nil without an error
+func TestGetPlan_NotFound(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("GetPlan", ctx, "Unknown").
+		Return(nil, nil).
+		Once()
+
+	res, err := adapter.GetPlan(ctx, "Unknown")
+
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestPutPlan checks that the adapter calls repo.PutPlan with the mapped entity
+func TestPutPlan(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("PutPlan", ctx, mock.MatchedBy(func(p plan.Plan) bool {
+			return p.PlanId == "Growth" &&
+				len(p.Prices) == 1 &&
+				p.Prices[0].PriceIds["test"] == "price_test" &&
+				p.Prices[0].Interval == "year" &&
+				p.Archived &&
+				!p.UpdatedAt.IsZero()
+		})).
+		Return(nil).
+		Once()
+
+	err := adapter.PutPlan(ctx, model.Plan{
+		PlanId:   "Growth",
+		Prices:   []model.PlanPrice{{Interval: model.BillingIntervalYear, ExternalPriceIds: map[string]string{"test": "price_test"}}},
+		Archived: true,
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetPlans checks that all entities are mapped to models
+func TestGetPlans(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("GetPlans", ctx).
+		Return([]plan.Plan{
+			{PlanId: "Core", TrialDays: 14},
+			{PlanId: "Legacy", Archived: true},
+		}, nil).
+		Once()
+
+	res, err := adapter.GetPlans(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Plan{
+		{PlanId: "Core", TrialDays: 14},
+		{PlanId: "Legacy", Archived: true},
+	}, res)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestCreatePlan_AlreadyExists checks that the conflict from the repository is passed through
+func TestCreatePlan_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := plan.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("CreatePlan", ctx, mock.MatchedBy(func(p plan.Plan) bool {
+			return p.PlanId == "Core" && p.TrialDays == 14
+		})).
+		Return(model.NewPlanAlreadyExistsErr("Core")).
+		Once()
+
+	err := adapter.CreatePlan(ctx, model.Plan{PlanId: "Core", TrialDays: 14})
+
+	assert.IsType(t, model.PlanAlreadyExistsErr{}, err)
+	mockRepo.AssertExpectations(t)
+}
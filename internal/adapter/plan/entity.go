@@ -0,0 +1,27 @@
+package plan
+
+import "time"
+
+type Plan struct {
+	PlanId    string    `dynamodbav:"PlanId"`
+	Name      string    `dynamodbav:"Name"`
+	Prices    []Price   `dynamodbav:"IntervalPrices"`
+	TrialDays int       `dynamodbav:"TrialDays"`
+	Features  []string  `dynamodbav:"Features"`
+	Archived  bool      `dynamodbav:"Archived"`
+	Version   int       `dynamodbav:"Version"`
+	UpdatedAt time.Time `dynamodbav:"UpdatedAt"`
+	// Plans stored before they had a price per billing interval have a single one in these attributes,
+	// they are only read. The next put of the plan stores the price in Prices instead.
+	LegacyPriceIds map[string]string `dynamodbav:"Prices,omitempty"`
+	LegacyAmount   int64             `dynamodbav:"Amount,omitempty"`
+	LegacyInterval string            `dynamodbav:"Interval,omitempty"`
+	LegacyCurrency string            `dynamodbav:"Currency,omitempty"`
+}
+
+type Price struct {
+	Interval string            `dynamodbav:"Interval"`
+	Amount   int64             `dynamodbav:"Amount"`
+	Currency string            `dynamodbav:"Currency"`
+	PriceIds map[string]string `dynamodbav:"PriceIds"`
+}
@@ -0,0 +1,92 @@
+package plan
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"time"
+)
+
+func mapToPlanEntity(plan model.Plan) Plan {
+	return Plan{
+		PlanId:    plan.PlanId,
+		Name:      plan.Name,
+		Prices:    mapToPriceEntities(plan.Prices),
+		TrialDays: plan.TrialDays,
+		Features:  plan.Features,
+		Archived:  plan.Archived,
+		Version:   plan.Version,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func mapToPlanModel(plan Plan) model.Plan {
+	prices := plan.Prices
+	if len(prices) == 0 && len(plan.LegacyPriceIds) > 0 {
+		prices = []Price{mapLegacyPrice(plan)}
+	}
+	return model.Plan{
+		PlanId:    plan.PlanId,
+		Name:      plan.Name,
+		Prices:    mapToPricesModel(prices),
+		TrialDays: plan.TrialDays,
+		Features:  plan.Features,
+		Archived:  plan.Archived,
+		Version:   plan.Version,
+	}
+}
+
+// mapLegacyPrice returns the single price of a plan stored before plans had one per billing interval
+func mapLegacyPrice(plan Plan) Price {
+	interval := plan.LegacyInterval
+	if interval == "" {
+		// Plans stored before billing intervals were introduced are monthly
+		interval = string(model.BillingIntervalMonth)
+	}
+	return Price{
+		Interval: interval,
+		Amount:   plan.LegacyAmount,
+		Currency: plan.LegacyCurrency,
+		PriceIds: plan.LegacyPriceIds,
+	}
+}
+
+func mapToPlansModel(plans []Plan) []model.Plan {
+	res := make([]model.Plan, 0, len(plans))
+	for _, plan := range plans {
+		res = append(res, mapToPlanModel(plan))
+	}
+	return res
+}
+
+func mapToPlanModelPtr(plan *Plan) *model.Plan {
+	if plan == nil {
+		return nil
+	}
+	res := mapToPlanModel(*plan)
+	return &res
+}
+
+func mapToPriceEntities(prices []model.PlanPrice) []Price {
+	res := make([]Price, 0, len(prices))
+	for _, price := range prices {
+		res = append(res, Price{
+			Interval: string(price.Interval),
+			Amount:   price.Amount,
+			Currency: price.Currency,
+			PriceIds: price.ExternalPriceIds,
+		})
+	}
+	return res
+}
+
+func mapToPricesModel(prices []Price) []model.PlanPrice {
+	var res []model.PlanPrice
+	for _, price := range prices {
+		res = append(res, model.PlanPrice{
+			Interval:         model.BillingInterval(price.Interval),
+			Amount:           price.Amount,
+			Currency:         price.Currency,
+			ExternalPriceIds: price.PriceIds,
+		})
+	}
+	return res
+}
@@ -0,0 +1,173 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+type Repository interface {
+	GetPlan(ctx context.Context, planId string) (*Plan, error)
+	GetPlans(ctx context.Context) ([]Plan, error)
+	CreatePlan(ctx context.Context, entity Plan) error
+	PutPlan(ctx context.Context, entity Plan) error
+}
+
+// catalogIndex is the GSI1 index, all plans of the catalog share one partition there
+const catalogIndex = "GSI1"
+
+const catalogPartition = "PLAN"
+
+type DynamoConfig struct {
+	Client       *dynamodb.Client
+	Table        string
+	QueryTimeout time.Duration
+}
+
+type dynamoRepository struct {
+	client       *dynamodb.Client
+	table        string
+	queryTimeout time.Duration
+}
+
+func NewDynamoRepository(config DynamoConfig) Repository {
+	return &dynamoRepository{
+		client:       config.Client,
+		table:        config.Table,
+		queryTimeout: config.QueryTimeout,
+	}
+}
+
+func (d *dynamoRepository) GetPlan(ctx context.Context, planId string) (*Plan, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	key := planKey(planId)
+
+	input := &dynamodb.GetItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: key},
+			"SK": &types.AttributeValueMemberS{Value: key},
+		},
+		TableName: aws.String(d.table),
+	}
+
+	result, err := d.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get dynamo plan entity")
+	}
+
+	return unmarshalPlanEntity(result)
+}
+
+// GetPlans returns all plans of the catalog, ordered by their id
+func (d *dynamoRepository) GetPlans(ctx context.Context) ([]Plan, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		IndexName:              aws.String(catalogIndex),
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: catalogPartition},
+		},
+	}
+
+	var entities []Plan
+	paginator := dynamodb.NewQueryPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query dynamo plan entities")
+		}
+		var pageEntities []Plan
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &pageEntities); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal dynamo plan entities")
+		}
+		entities = append(entities, pageEntities...)
+	}
+
+	return entities, nil
+}
+
+// CreatePlan fails with a model.PlanAlreadyExistsErr if there is a plan with the same id
+func (d *dynamoRepository) CreatePlan(ctx context.Context, entity Plan) error {
+	err := d.putPlan(ctx, entity, aws.String("attribute_not_exists(PK)"), nil)
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return model.NewPlanAlreadyExistsErr(entity.PlanId)
+	}
+	return err
+}
+
+// PutPlan replaces the plan as a whole and moves its version forward. Version is the one the plan was
+// read at, the put fails with a model.StalePlanUpdateErr if the plan was replaced since. Plans stored
+// before they had a version are at version 0.
+func (d *dynamoRepository) PutPlan(ctx context.Context, entity Plan) error {
+	version := &types.AttributeValueMemberN{Value: strconv.Itoa(entity.Version)}
+	entity.Version++
+	err := d.putPlan(ctx, entity, aws.String("attribute_exists(PK) AND (attribute_not_exists(Version) OR Version = :version)"),
+		map[string]types.AttributeValue{":version": version})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if conditionErr.Item == nil {
+			return model.NewPlanNotFoundErr(entity.PlanId)
+		}
+		return model.NewStalePlanUpdateErr(entity.PlanId)
+	}
+	return err
+}
+
+func (d *dynamoRepository) putPlan(ctx context.Context, entity Plan, condition *string, values map[string]types.AttributeValue) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	atr, err := attributevalue.MarshalMap(&entity)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo plan entity")
+	}
+
+	key := planKey(entity.PlanId)
+	atr["PK"] = &types.AttributeValueMemberS{Value: key}
+	atr["SK"] = &types.AttributeValueMemberS{Value: key}
+	atr["GSI1PK"] = &types.AttributeValueMemberS{Value: catalogPartition}
+	atr["GSI1SK"] = &types.AttributeValueMemberS{Value: key}
+
+	input := &dynamodb.PutItemInput{
+		Item:                                atr,
+		TableName:                           aws.String(d.table),
+		ConditionExpression:                 condition,
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	_, err = d.client.PutItem(ctx, input)
+	if err != nil {
+		return errors.Wrapf(err, "failed to put dynamo plan entity")
+	}
+
+	return nil
+}
+
+func planKey(planId string) string {
+	return fmt.Sprintf("PLAN#%s", planId)
+}
+
+func unmarshalPlanEntity(result *dynamodb.GetItemOutput) (*Plan, error) {
+	if result.Item == nil {
+		return nil, nil
+	}
+	var entity Plan
+	if err := attributevalue.UnmarshalMap(result.Item, &entity); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal dynamo plan entity")
+	}
+	return &entity, nil
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package plan_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DenisBarabanshchikov/subscription/config"
+	"github.com/DenisBarabanshchikov/subscription/internal/adapter/plan"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+func TestDynamoRepository_PutAndGetPlan(t *testing.T) {
+	repo := plan.NewDynamoRepository(config.ProvidePlanDynamoConfig())
+	ctx := context.Background()
+
+	planId := fmt.Sprintf("test-plan-%d", time.Now().UnixNano())
+	entity := plan.Plan{
+		PlanId:    planId,
+		Name:      "Test plan",
+		Prices:    []plan.Price{{Interval: "month", Amount: 900, Currency: "usd", PriceIds: map[string]string{"test": "price_test"}}},
+		Features:  []string{"feature"},
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	// Only stored plans can be replaced
+	err := repo.PutPlan(ctx, entity)
+	assert.IsType(t, model.PlanNotFoundErr{}, err)
+
+	err = repo.CreatePlan(ctx, entity)
+	assert.NoError(t, err, "failed to create plan")
+
+	// A put replaces the plan and moves its version forward
+	entity.Prices[0].PriceIds["live"] = "price_live"
+	entity.Archived = true
+	err = repo.PutPlan(ctx, entity)
+	assert.NoError(t, err, "failed to put plan")
+
+	retrieved, err := repo.GetPlan(ctx, planId)
+	assert.NoError(t, err, "failed to get plan")
+	assert.NotNil(t, retrieved, "plan not found")
+	assert.Equal(t, map[string]string{"test": "price_test", "live": "price_live"}, retrieved.Prices[0].PriceIds)
+	assert.True(t, retrieved.Archived)
+	assert.Equal(t, 1, retrieved.Version)
+
+	// A put of the version read before is stale
+	entity.Archived = false
+	err = repo.PutPlan(ctx, entity)
+	assert.IsType(t, model.StalePlanUpdateErr{}, err)
+
+	missing, err := repo.GetPlan(ctx, planId+"-missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestDynamoRepository_CreateAndListPlans(t *testing.T) {
+	repo := plan.NewDynamoRepository(config.ProvidePlanDynamoConfig())
+	ctx := context.Background()
+
+	planId := fmt.Sprintf("test-plan-%d", time.Now().UnixNano())
+	entity := plan.Plan{
+		PlanId: planId,
+		Name:   "Test plan",
+		Prices: []plan.Price{
+			{Interval: "month", Amount: 900, Currency: "usd"},
+			{Interval: "year", Amount: 9000, Currency: "usd"},
+		},
+		TrialDays: 14,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	err := repo.CreatePlan(ctx, entity)
+	assert.NoError(t, err, "failed to create plan")
+
+	// The plan id is taken now
+	err = repo.CreatePlan(ctx, entity)
+	assert.IsType(t, model.PlanAlreadyExistsErr{}, err)
+
+	plans, err := repo.GetPlans(ctx)
+	assert.NoError(t, err, "failed to get plans")
+
+	var listed *plan.Plan
+	for i := range plans {
+		if plans[i].PlanId == planId {
+			listed = &plans[i]
+		}
+	}
+	assert.NotNil(t, listed, "plan not listed")
+	assert.Len(t, listed.Prices, 2)
+	assert.Equal(t, 14, listed.TrialDays)
+}
+
+// TestDynamoRepository_GetLegacyPlan reads a plan stored with a single price, before plans had one per
+// billing interval, and replaces it with the current layout
+func TestDynamoRepository_GetLegacyPlan(t *testing.T) {
+	cfg := config.ProvidePlanDynamoConfig()
+	repo := plan.NewDynamoRepository(cfg)
+	ctx := context.Background()
+
+	planId := fmt.Sprintf("test-plan-%d", time.Now().UnixNano())
+	key := "PLAN#" + planId
+	_, err := cfg.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.Table),
+		Item: map[string]types.AttributeValue{
+			"PK":       &types.AttributeValueMemberS{Value: key},
+			"SK":       &types.AttributeValueMemberS{Value: key},
+			"GSI1PK":   &types.AttributeValueMemberS{Value: "PLAN"},
+			"GSI1SK":   &types.AttributeValueMemberS{Value: key},
+			"PlanId":   &types.AttributeValueMemberS{Value: planId},
+			"Name":     &types.AttributeValueMemberS{Value: "Legacy plan"},
+			"Prices":   &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"test": &types.AttributeValueMemberS{Value: "price_test"}}},
+			"Amount":   &types.AttributeValueMemberN{Value: "900"},
+			"Interval": &types.AttributeValueMemberS{Value: "year"},
+			"Currency": &types.AttributeValueMemberS{Value: "usd"},
+		},
+	})
+	assert.NoError(t, err, "failed to put legacy plan")
+
+	retrieved, err := repo.GetPlan(ctx, planId)
+	assert.NoError(t, err, "failed to get plan")
+	assert.NotNil(t, retrieved, "plan not found")
+	assert.Empty(t, retrieved.Prices)
+	assert.Equal(t, map[string]string{"test": "price_test"}, retrieved.LegacyPriceIds)
+	assert.Equal(t, int64(900), retrieved.LegacyAmount)
+	assert.Equal(t, "year", retrieved.LegacyInterval)
+	assert.Equal(t, "usd", retrieved.LegacyCurrency)
+
+	// A put stores the price in the current layout only
+	replaced := *retrieved
+	replaced.Prices = []plan.Price{{Interval: "year", Amount: 900, Currency: "usd", PriceIds: map[string]string{"test": "price_test"}}}
+	replaced.LegacyPriceIds = nil
+	replaced.LegacyAmount = 0
+	replaced.LegacyInterval = ""
+	replaced.LegacyCurrency = ""
+	err = repo.PutPlan(ctx, replaced)
+	assert.NoError(t, err, "failed to put plan")
+
+	retrieved, err = repo.GetPlan(ctx, planId)
+	assert.NoError(t, err, "failed to get plan")
+	assert.Len(t, retrieved.Prices, 1)
+	assert.Nil(t, retrieved.LegacyPriceIds)
+}
@@ -28,6 +28,10 @@ func (a *adapter) GetCustomer(ctx context.Context, id string) (*model.Customer,
 	return mapToCustomerModelPtr(customer), nil
 }
 
+func (a *adapter) SetCustomerCurrency(ctx context.Context, customerId, currency string) error {
+	return a.repository.SetCustomerCurrency(ctx, customerId, currency)
+}
+
 func (a *adapter) CreateSubscription(ctx context.Context, subscription model.Subscription) error {
 	return a.repository.CreateSubscription(ctx, mapSubscriptionToEntity(subscription))
 }
@@ -39,3 +43,51 @@ func (a *adapter) GetSubscription(ctx context.Context, customerId, subscriptionI
 	}
 	return mapSubscriptionToModelPtr(subscription), nil
 }
+
+func (a *adapter) GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*model.Customer, error) {
+	customer, err := a.repository.GetCustomerByExternalId(ctx, externalCustomerId)
+	if err != nil {
+		return nil, err
+	}
+	return mapToCustomerModelPtr(customer), nil
+}
+
+func (a *adapter) GetSubscriptions(ctx context.Context, customerId string) ([]model.Subscription, error) {
+	subscriptions, err := a.repository.GetSubscriptions(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	return mapSubscriptionsToModel(subscriptions), nil
+}
+
+func (a *adapter) GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*model.Subscription, error) {
+	subscription, err := a.repository.GetSubscriptionByExternalId(ctx, externalSubscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	return mapSubscriptionToModelPtr(subscription), nil
+}
+
+func (a *adapter) UpdateSubscriptionStatus(ctx context.Context, subscription model.Subscription, change *model.StatusChange) error {
+	return a.repository.UpdateSubscriptionStatus(ctx, mapSubscriptionToEntity(subscription), mapToStatusHistoryEntity(subscription, change))
+}
+
+func (a *adapter) UpdateSubscriptionPlan(ctx context.Context, subscription model.Subscription) error {
+	return a.repository.UpdateSubscriptionPlan(ctx, mapSubscriptionToEntity(subscription))
+}
+
+func (a *adapter) UpdateSubscriptionQuantity(ctx context.Context, subscription model.Subscription) error {
+	return a.repository.UpdateSubscriptionQuantity(ctx, mapSubscriptionToEntity(subscription))
+}
+
+func (a *adapter) UpdateSubscriptionAddOns(ctx context.Context, subscription model.Subscription) error {
+	return a.repository.UpdateSubscriptionAddOns(ctx, mapSubscriptionToEntity(subscription))
+}
+
+func (a *adapter) GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]model.StatusChange, error) {
+	history, err := a.repository.GetStatusHistory(ctx, customerId, subscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	return mapToStatusChangesModel(history), nil
+}
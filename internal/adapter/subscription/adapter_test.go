@@ -34,6 +34,11 @@ func (m *mockRepository) GetCustomer(ctx context.Context, id string) (*subscript
 	return nil, args.Error(1)
 }
 
+func (m *mockRepository) SetCustomerCurrency(ctx context.Context, customerId, currency string) error {
+	args := m.Called(ctx, customerId, currency)
+	return args.Error(0)
+}
+
 func (m *mockRepository) CreateSubscription(ctx context.Context, sub subscription.Subscription) error {
 	args := m.Called(ctx, sub)
 	return args.Error(0)
@@ -47,6 +52,58 @@ func (m *mockRepository) GetSubscription(ctx context.Context, customerId, subscr
 	return nil, args.Error(1)
 }
 
+func (m *mockRepository) GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*subscription.Customer, error) {
+	args := m.Called(ctx, externalCustomerId)
+	if ce, ok := args.Get(0).(*subscription.Customer); ok {
+		return ce, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) GetSubscriptions(ctx context.Context, customerId string) ([]subscription.Subscription, error) {
+	args := m.Called(ctx, customerId)
+	if se, ok := args.Get(0).([]subscription.Subscription); ok {
+		return se, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*subscription.Subscription, error) {
+	args := m.Called(ctx, externalSubscriptionId)
+	if se, ok := args.Get(0).(*subscription.Subscription); ok {
+		return se, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) UpdateSubscriptionStatus(ctx context.Context, sub subscription.Subscription, history *subscription.StatusHistory) error {
+	args := m.Called(ctx, sub, history)
+	return args.Error(0)
+}
+
+func (m *mockRepository) UpdateSubscriptionPlan(ctx context.Context, sub subscription.Subscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *mockRepository) UpdateSubscriptionQuantity(ctx context.Context, sub subscription.Subscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *mockRepository) UpdateSubscriptionAddOns(ctx context.Context, sub subscription.Subscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]subscription.StatusHistory, error) {
+	args := m.Called(ctx, customerId, subscriptionId)
+	if history, ok := args.Get(0).([]subscription.StatusHistory); ok {
+		return history, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // TestCreateCustomer checks that the adapter calls repo.CreateCustomer with correct data
 func TestCreateCustomer(t *testing.T) {
 	ctx := context.Background()
@@ -113,6 +170,7 @@ func TestGetCustomer(t *testing.T) {
 	// The repository returns an entity.
 	repoEntity := subscription.Customer{
 		CustomerId: "cust_123",
+		Currency:   "eur",
 		// For this test, CreatedAt and UpdatedAt can be set to any value.
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -126,6 +184,23 @@ func TestGetCustomer(t *testing.T) {
 	assert.NoError(t, err)
 	// Check that the domain model is mapped from the returned entity
 	assert.Equal(t, "cust_123", cust.CustomerId)
+	assert.Equal(t, "eur", cust.Currency)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSetCustomerCurrency_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("SetCustomerCurrency", ctx, "cust_123", "usd").
+		Return(model.NewCurrencyMismatchErr("cust_123", "usd")).
+		Once()
+
+	err := adapter.SetCustomerCurrency(ctx, "cust_123", "usd")
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -222,7 +297,9 @@ func TestGetSubscription(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "sub_456", sub.SubscriptionId)
 	assert.Equal(t, "cust_123", sub.CustomerId)
-	assert.Equal(t, "incomplete", sub.Status)
+	assert.Equal(t, model.SubscriptionStatusIncomplete, sub.Status)
+	// Entities stored without a quantity have a single seat.
+	assert.Equal(t, 1, sub.Quantity)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -247,3 +324,183 @@ func TestGetSubscription_Error(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetCustomerByExternalId(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	repoEntity := subscription.Customer{
+		CustomerId:         "cust_123",
+		ExternalCustomerId: "cus_67890",
+	}
+	mockRepo.
+		On("GetCustomerByExternalId", ctx, "cus_67890").
+		Return(&repoEntity, nil).
+		Once()
+
+	cust, err := adapter.GetCustomerByExternalId(ctx, "cus_67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "cust_123", cust.CustomerId)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	repoEntities := []subscription.Subscription{
+		{SubscriptionId: "sub_1", CustomerId: "cust_123", Status: "active"},
+		{SubscriptionId: "sub_2", CustomerId: "cust_123", Status: "canceled"},
+	}
+	mockRepo.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return(repoEntities, nil).
+		Once()
+
+	subs, err := adapter.GetSubscriptions(ctx, "cust_123")
+	assert.NoError(t, err)
+	assert.Len(t, subs, 2)
+	assert.Equal(t, "sub_1", subs[0].SubscriptionId)
+	assert.Equal(t, model.SubscriptionStatusCanceled, subs[1].Status)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetSubscriptionByExternalId(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	mockRepo.
+		On("GetSubscriptionByExternalId", ctx, "sub_ext").
+		Return((*subscription.Subscription)(nil), nil).
+		Once()
+
+	sub, err := adapter.GetSubscriptionByExternalId(ctx, "sub_ext")
+	assert.NoError(t, err)
+	assert.Nil(t, sub)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateSubscriptionStatus(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	lastEventAt := time.Unix(1700000000, 0).UTC()
+	sub := model.Subscription{
+		SubscriptionId: "sub_abc",
+		CustomerId:     "cust_123",
+		Status:         "active",
+		LastEventAt:    &lastEventAt,
+	}
+
+	mockRepo.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s subscription.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" &&
+				s.CustomerId == "cust_123" &&
+				s.Status == "active" &&
+				s.LastEventAt.Equal(lastEventAt) &&
+				!s.UpdatedAt.IsZero()
+		}), (*subscription.StatusHistory)(nil)).
+		Return(nil).
+		Once()
+
+	err := adapter.UpdateSubscriptionStatus(ctx, sub, nil)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestUpdateSubscriptionStatusWithStatusChange checks that the change is stored as a history item of the subscription
+func TestUpdateSubscriptionStatusWithStatusChange(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	changedAt := time.Unix(1700000000, 0).UTC()
+	sub := model.Subscription{
+		SubscriptionId: "sub_abc",
+		CustomerId:     "cust_123",
+		Status:         model.SubscriptionStatusPastDue,
+	}
+	change := &model.StatusChange{
+		PreviousStatus: model.SubscriptionStatusActive,
+		Status:         model.SubscriptionStatusPastDue,
+		Source:         model.StatusChangeSourceWebhook,
+		EventId:        "evt_123",
+		ChangedAt:      changedAt,
+	}
+
+	mockRepo.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, &subscription.StatusHistory{
+			SubscriptionId: "sub_abc",
+			CustomerId:     "cust_123",
+			PreviousStatus: "active",
+			Status:         "past_due",
+			Source:         "webhook",
+			EventId:        "evt_123",
+			ChangedAt:      changedAt,
+		}).
+		Return(nil).
+		Once()
+
+	err := adapter.UpdateSubscriptionStatus(ctx, sub, change)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateSubscriptionAddOns(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	sub := model.Subscription{
+		SubscriptionId: "sub_abc",
+		CustomerId:     "cust_123",
+		AddOns:         []model.AddOn{{Name: "ExtraStorage", ExternalItemId: "si_123", Quantity: 2}},
+	}
+
+	mockRepo.
+		On("UpdateSubscriptionAddOns", ctx, mock.MatchedBy(func(s subscription.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" &&
+				s.CustomerId == "cust_123" &&
+				len(s.AddOns) == 1 && s.AddOns[0].Name == "ExtraStorage" && s.AddOns[0].ExternalItemId == "si_123" &&
+				!s.UpdatedAt.IsZero()
+		})).
+		Return(nil).
+		Once()
+
+	err := adapter.UpdateSubscriptionAddOns(ctx, sub)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetStatusHistory(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(mockRepository)
+	adapter := subscription.NewAdapter(mockRepo)
+
+	changedAt := time.Unix(1700000000, 0).UTC()
+	mockRepo.
+		On("GetStatusHistory", ctx, "cust_123", "sub_abc").
+		Return([]subscription.StatusHistory{
+			{SubscriptionId: "sub_abc", CustomerId: "cust_123", PreviousStatus: "active", Status: "past_due", Source: "reconciliation", ChangedAt: changedAt},
+		}, nil).
+		Once()
+
+	history, err := adapter.GetStatusHistory(ctx, "cust_123", "sub_abc")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.StatusChange{
+		{
+			PreviousStatus: model.SubscriptionStatusActive,
+			Status:         model.SubscriptionStatusPastDue,
+			Source:         model.StatusChangeSourceReconciliation,
+			ChangedAt:      changedAt,
+		},
+	}, history)
+	mockRepo.AssertExpectations(t)
+}
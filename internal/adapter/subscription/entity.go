@@ -5,16 +5,41 @@ import "time"
 type Customer struct {
 	CustomerId         string    `dynamodbav:"CustomerId"`
 	ExternalCustomerId string    `dynamodbav:"ExternalCustomerId"`
+	Currency           string    `dynamodbav:"Currency,omitempty"`
 	CreatedAt          time.Time `dynamodbav:"CreatedAt"`
 	UpdatedAt          time.Time `dynamodbav:"UpdatedAt"`
 }
 
 type Subscription struct {
-	SubscriptionId         string    `dynamodbav:"SubscriptionId"`
-	CustomerId             string    `dynamodbav:"CustomerId"`
-	ExternalSubscriptionID string    `dynamodbav:"ExternalSubscriptionId"`
-	Plan                   string    `dynamodbav:"Plan"`
-	Status                 string    `dynamodbav:"Status"`
-	CreatedAt              time.Time `dynamodbav:"CreatedAt"`
-	UpdatedAt              time.Time `dynamodbav:"UpdatedAt"`
+	SubscriptionId         string     `dynamodbav:"SubscriptionId"`
+	CustomerId             string     `dynamodbav:"CustomerId"`
+	ExternalSubscriptionID string     `dynamodbav:"ExternalSubscriptionId"`
+	Plan                   string     `dynamodbav:"Plan"`
+	Interval               string     `dynamodbav:"Interval"`
+	Currency               string     `dynamodbav:"Currency,omitempty"`
+	Quantity               int        `dynamodbav:"Quantity"`
+	AddOns                 []AddOn    `dynamodbav:"AddOns,omitempty"`
+	Status                 string     `dynamodbav:"Status"`
+	CancelAtPeriodEnd      bool       `dynamodbav:"CancelAtPeriodEnd"`
+	TrialEnd               *time.Time `dynamodbav:"TrialEnd,omitempty"`
+	LastEventAt            *time.Time `dynamodbav:"LastEventAt,omitempty,unixtime"`
+	SyncedAt               *time.Time `dynamodbav:"SyncedAt,omitempty"`
+	CreatedAt              time.Time  `dynamodbav:"CreatedAt"`
+	UpdatedAt              time.Time  `dynamodbav:"UpdatedAt"`
+}
+
+type AddOn struct {
+	Name           string `dynamodbav:"Name"`
+	ExternalItemId string `dynamodbav:"ExternalItemId"`
+	Quantity       int    `dynamodbav:"Quantity"`
+}
+
+type StatusHistory struct {
+	SubscriptionId string    `dynamodbav:"SubscriptionId"`
+	CustomerId     string    `dynamodbav:"CustomerId"`
+	PreviousStatus string    `dynamodbav:"PreviousStatus"`
+	Status         string    `dynamodbav:"Status"`
+	Source         string    `dynamodbav:"Source"`
+	EventId        string    `dynamodbav:"EventId,omitempty"`
+	ChangedAt      time.Time `dynamodbav:"ChangedAt"`
 }
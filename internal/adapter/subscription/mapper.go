@@ -9,6 +9,7 @@ func mapToCustomerEntity(customer model.Customer) Customer {
 	return Customer{
 		CustomerId:         customer.CustomerId,
 		ExternalCustomerId: customer.ExternalCustomerId,
+		Currency:           customer.Currency,
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 	}
@@ -18,6 +19,7 @@ func mapToCustomerModel(customer Customer) model.Customer {
 	return model.Customer{
 		CustomerId:         customer.CustomerId,
 		ExternalCustomerId: customer.ExternalCustomerId,
+		Currency:           customer.Currency,
 	}
 }
 
@@ -35,19 +37,45 @@ func mapSubscriptionToEntity(subscription model.Subscription) Subscription {
 		CustomerId:             subscription.CustomerId,
 		ExternalSubscriptionID: subscription.ExternalSubscriptionID,
 		Plan:                   subscription.Plan,
-		Status:                 subscription.Status,
+		Interval:               string(subscription.Interval),
+		Currency:               subscription.Currency,
+		Quantity:               subscription.Quantity,
+		AddOns:                 mapToAddOnEntities(subscription.AddOns),
+		Status:                 string(subscription.Status),
+		CancelAtPeriodEnd:      subscription.CancelAtPeriodEnd,
+		TrialEnd:               subscription.TrialEnd,
+		LastEventAt:            subscription.LastEventAt,
+		SyncedAt:               subscription.SyncedAt,
 		CreatedAt:              time.Now(),
 		UpdatedAt:              time.Now(),
 	}
 }
 
 func mapSubscriptionToModel(subscription Subscription) model.Subscription {
+	quantity := subscription.Quantity
+	if quantity == 0 {
+		// Subscriptions stored before seats were introduced have a single one
+		quantity = 1
+	}
+	interval := model.BillingInterval(subscription.Interval)
+	if interval == "" {
+		// Subscriptions stored before billing intervals were introduced are monthly
+		interval = model.BillingIntervalMonth
+	}
 	return model.Subscription{
 		SubscriptionId:         subscription.SubscriptionId,
 		CustomerId:             subscription.CustomerId,
 		ExternalSubscriptionID: subscription.ExternalSubscriptionID,
 		Plan:                   subscription.Plan,
-		Status:                 subscription.Status,
+		Interval:               interval,
+		Currency:               subscription.Currency,
+		Quantity:               quantity,
+		AddOns:                 mapToAddOnsModel(subscription.AddOns),
+		Status:                 model.SubscriptionStatus(subscription.Status),
+		CancelAtPeriodEnd:      subscription.CancelAtPeriodEnd,
+		TrialEnd:               subscription.TrialEnd,
+		LastEventAt:            subscription.LastEventAt,
+		SyncedAt:               subscription.SyncedAt,
 	}
 }
 
@@ -58,3 +86,70 @@ func mapSubscriptionToModelPtr(subscription *Subscription) *model.Subscription {
 	res := mapSubscriptionToModel(*subscription)
 	return &res
 }
+
+func mapSubscriptionsToModel(subscriptions []Subscription) []model.Subscription {
+	res := make([]model.Subscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		res = append(res, mapSubscriptionToModel(subscription))
+	}
+	return res
+}
+
+func mapToStatusHistoryEntity(subscription model.Subscription, change *model.StatusChange) *StatusHistory {
+	if change == nil {
+		return nil
+	}
+	return &StatusHistory{
+		SubscriptionId: subscription.SubscriptionId,
+		CustomerId:     subscription.CustomerId,
+		PreviousStatus: string(change.PreviousStatus),
+		Status:         string(change.Status),
+		Source:         string(change.Source),
+		EventId:        change.EventId,
+		ChangedAt:      change.ChangedAt,
+	}
+}
+
+func mapToStatusChangesModel(history []StatusHistory) []model.StatusChange {
+	res := make([]model.StatusChange, 0, len(history))
+	for _, change := range history {
+		res = append(res, model.StatusChange{
+			PreviousStatus: model.SubscriptionStatus(change.PreviousStatus),
+			Status:         model.SubscriptionStatus(change.Status),
+			Source:         model.StatusChangeSource(change.Source),
+			EventId:        change.EventId,
+			ChangedAt:      change.ChangedAt,
+		})
+	}
+	return res
+}
+
+func mapToAddOnEntities(addOns []model.AddOn) []AddOn {
+	if len(addOns) == 0 {
+		return nil
+	}
+	res := make([]AddOn, 0, len(addOns))
+	for _, addOn := range addOns {
+		res = append(res, AddOn{
+			Name:           addOn.Name,
+			ExternalItemId: addOn.ExternalItemId,
+			Quantity:       addOn.Quantity,
+		})
+	}
+	return res
+}
+
+func mapToAddOnsModel(addOns []AddOn) []model.AddOn {
+	if len(addOns) == 0 {
+		return nil
+	}
+	res := make([]model.AddOn, 0, len(addOns))
+	for _, addOn := range addOns {
+		res = append(res, model.AddOn{
+			Name:           addOn.Name,
+			ExternalItemId: addOn.ExternalItemId,
+			Quantity:       addOn.Quantity,
+		})
+	}
+	return res
+}
@@ -3,21 +3,38 @@ package subscription
 import (
 	"context"
 	"fmt"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/pkg/errors"
+	"strconv"
 	"time"
 )
 
 type Repository interface {
 	CreateCustomer(ctx context.Context, entity Customer) error
 	GetCustomer(ctx context.Context, customerId string) (*Customer, error)
+	SetCustomerCurrency(ctx context.Context, customerId, currency string) error
 	CreateSubscription(ctx context.Context, entity Subscription) error
 	GetSubscription(ctx context.Context, customerId, subscriptionId string) (*Subscription, error)
+	GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*Customer, error)
+	GetSubscriptions(ctx context.Context, customerId string) ([]Subscription, error)
+	GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*Subscription, error)
+	UpdateSubscriptionStatus(ctx context.Context, entity Subscription, history *StatusHistory) error
+	UpdateSubscriptionPlan(ctx context.Context, entity Subscription) error
+	UpdateSubscriptionQuantity(ctx context.Context, entity Subscription) error
+	UpdateSubscriptionAddOns(ctx context.Context, entity Subscription) error
+	GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]StatusHistory, error)
 }
 
+// externalIdIndex is the GSI1 index which resolves provider ids to our items
+const externalIdIndex = "GSI1"
+
+// historyTimeFormat has a fixed length, so the history of a subscription sorts by time
+const historyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
 type DynamoConfig struct {
 	Client       *dynamodb.Client
 	Table        string
@@ -51,6 +68,8 @@ func (d *dynamoRepository) CreateCustomer(ctx context.Context, entity Customer)
 	sk := fmt.Sprintf("CUSTOMER#%s", entity.CustomerId)
 	atr["PK"] = &types.AttributeValueMemberS{Value: pk}
 	atr["SK"] = &types.AttributeValueMemberS{Value: sk}
+	atr["GSI1PK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("EXTERNAL_CUSTOMER#%s", entity.ExternalCustomerId)}
+	atr["GSI1SK"] = &types.AttributeValueMemberS{Value: sk}
 
 	input := &dynamodb.PutItemInput{
 		Item:                atr,
@@ -89,6 +108,54 @@ func (d *dynamoRepository) GetCustomer(ctx context.Context, customerId string) (
 	return unmarshalCustomerEntity(result)
 }
 
+// SetCustomerCurrency sets the currency of a customer who has none yet. A customer with another
+// currency is rejected with model.CurrencyMismatchErr, setting the same one again is a no-op.
+func (d *dynamoRepository) SetCustomerCurrency(ctx context.Context, customerId, currency string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(time.Now())
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo customer updated at")
+	}
+
+	pk := fmt.Sprintf("CUSTOMER#%s", customerId)
+	sk := fmt.Sprintf("CUSTOMER#%s", customerId)
+
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		TableName:           aws.String(d.table),
+		UpdateExpression:    aws.String("SET #currency = :currency, UpdatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_exists(PK) AND (attribute_not_exists(#currency) OR #currency = :currency)"),
+		ExpressionAttributeNames: map[string]string{
+			"#currency": "Currency",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":currency":  &types.AttributeValueMemberS{Value: currency},
+			":updatedAt": updatedAt,
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if conditionErr.Item == nil {
+			return model.NewCustomerNotFoundErr(customerId)
+		}
+		return model.NewCurrencyMismatchErr(customerId, currency)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to update dynamo customer currency")
+	}
+
+	return nil
+}
+
+// CreateSubscription fails with a model.SubscriptionAlreadyExistsErr if a subscription with the same
+// provider id was created before. A marker item keyed by the provider id is written in the same
+// transaction, GSI1 is eventually consistent and can't guard the write.
 func (d *dynamoRepository) CreateSubscription(ctx context.Context, entity Subscription) error {
 	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
 	defer cancel()
@@ -102,14 +169,35 @@ func (d *dynamoRepository) CreateSubscription(ctx context.Context, entity Subscr
 	sk := fmt.Sprintf("SUBSCRIPTION#%s", entity.SubscriptionId)
 	atr["PK"] = &types.AttributeValueMemberS{Value: pk}
 	atr["SK"] = &types.AttributeValueMemberS{Value: sk}
+	atr["GSI1PK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("EXTERNAL_SUBSCRIPTION#%s", entity.ExternalSubscriptionID)}
+	atr["GSI1SK"] = &types.AttributeValueMemberS{Value: sk}
 
-	input := &dynamodb.PutItemInput{
-		Item:                atr,
-		TableName:           aws.String(d.table),
-		ConditionExpression: aws.String("attribute_not_exists(PK)"),
-	}
+	marker := fmt.Sprintf("EXTERNAL_SUBSCRIPTION#%s", entity.ExternalSubscriptionID)
 
-	_, err = d.client.PutItem(ctx, input)
+	_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				Item: map[string]types.AttributeValue{
+					"PK":             &types.AttributeValueMemberS{Value: marker},
+					"SK":             &types.AttributeValueMemberS{Value: marker},
+					"SubscriptionId": &types.AttributeValueMemberS{Value: entity.SubscriptionId},
+					"CustomerId":     &types.AttributeValueMemberS{Value: entity.CustomerId},
+				},
+				TableName:           aws.String(d.table),
+				ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			}},
+			{Put: &types.Put{
+				Item:                atr,
+				TableName:           aws.String(d.table),
+				ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			}},
+		},
+	})
+	var canceledErr *types.TransactionCanceledException
+	if errors.As(err, &canceledErr) && len(canceledErr.CancellationReasons) > 0 &&
+		aws.ToString(canceledErr.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+		return model.NewSubscriptionAlreadyExistsErr(entity.ExternalSubscriptionID)
+	}
 	if err != nil {
 		return errors.Wrapf(err, "failed to put dynamo subscription entity")
 	}
@@ -140,6 +228,345 @@ func (d *dynamoRepository) GetSubscription(ctx context.Context, customerId, subs
 	return unmarshalSubscriptionEntity(result)
 }
 
+func (d *dynamoRepository) GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*Customer, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	item, err := d.findExternalId(ctx, fmt.Sprintf("EXTERNAL_CUSTOMER#%s", externalCustomerId), "CUSTOMER#", "ExternalCustomerId", externalCustomerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query dynamo customer entity by external id")
+	}
+
+	return unmarshalCustomerEntity(&dynamodb.GetItemOutput{Item: item})
+}
+
+func (d *dynamoRepository) GetSubscriptions(ctx context.Context, customerId string) ([]Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("CUSTOMER#%s", customerId)},
+			":sk": &types.AttributeValueMemberS{Value: "SUBSCRIPTION#"},
+		},
+	}
+
+	var entities []Subscription
+	paginator := dynamodb.NewQueryPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query dynamo subscription entities")
+		}
+		var pageEntities []Subscription
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &pageEntities); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal dynamo subscription entities")
+		}
+		entities = append(entities, pageEntities...)
+	}
+
+	return entities, nil
+}
+
+func (d *dynamoRepository) GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	item, err := d.findExternalId(ctx, fmt.Sprintf("EXTERNAL_SUBSCRIPTION#%s", externalSubscriptionId), "SUBSCRIPTION#", "ExternalSubscriptionId", externalSubscriptionId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query dynamo subscription entity by external id")
+	}
+
+	return unmarshalSubscriptionEntity(&dynamodb.GetItemOutput{Item: item})
+}
+
+// UpdateSubscriptionStatus writes the status and the cancellation of the subscription. When LastEventAt
+// is set the write is rejected with model.StaleSubscriptionUpdateErr if the item was synced from a
+// newer event. SyncedAt is written when set. A status change is appended to the history of the subscription in the same transaction.
+func (d *dynamoRepository) UpdateSubscriptionStatus(ctx context.Context, entity Subscription, history *StatusHistory) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(entity.UpdatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo subscription updated at")
+	}
+
+	pk := fmt.Sprintf("CUSTOMER#%s", entity.CustomerId)
+	sk := fmt.Sprintf("SUBSCRIPTION#%s", entity.SubscriptionId)
+
+	update := &types.Update{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		TableName:           aws.String(d.table),
+		UpdateExpression:    aws.String("SET #status = :status, CancelAtPeriodEnd = :cancelAtPeriodEnd, UpdatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":            &types.AttributeValueMemberS{Value: entity.Status},
+			":cancelAtPeriodEnd": &types.AttributeValueMemberBOOL{Value: entity.CancelAtPeriodEnd},
+			":updatedAt":         updatedAt,
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	if entity.LastEventAt != nil {
+		update.UpdateExpression = aws.String(*update.UpdateExpression + ", LastEventAt = :lastEventAt")
+		update.ConditionExpression = aws.String("attribute_exists(PK) AND (attribute_not_exists(LastEventAt) OR LastEventAt <= :lastEventAt)")
+		update.ExpressionAttributeValues[":lastEventAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(entity.LastEventAt.Unix(), 10)}
+	}
+	if entity.SyncedAt != nil {
+		syncedAt, err := attributevalue.Marshal(entity.SyncedAt)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal dynamo subscription synced at")
+		}
+		update.UpdateExpression = aws.String(*update.UpdateExpression + ", SyncedAt = :syncedAt")
+		update.ExpressionAttributeValues[":syncedAt"] = syncedAt
+	}
+
+	if history == nil {
+		return d.updateSubscription(ctx, entity, update)
+	}
+
+	atr, err := attributevalue.MarshalMap(history)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo status history entity")
+	}
+	atr["PK"] = &types.AttributeValueMemberS{Value: pk}
+	atr["SK"] = &types.AttributeValueMemberS{Value: statusHistoryKey(history.SubscriptionId, history.ChangedAt)}
+
+	_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: update},
+			{Put: &types.Put{Item: atr, TableName: aws.String(d.table)}},
+		},
+	})
+	var canceledErr *types.TransactionCanceledException
+	if errors.As(err, &canceledErr) && len(canceledErr.CancellationReasons) > 0 &&
+		aws.ToString(canceledErr.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+		return conditionFailedErr(entity, canceledErr.CancellationReasons[0].Item)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to update dynamo subscription entity with status history")
+	}
+
+	return nil
+}
+
+// UpdateSubscriptionPlan writes the plan and the billing interval of the subscription. Like the other
+// attribute updates it doesn't depend on LastEventAt, so it can't overwrite a status synced meanwhile.
+func (d *dynamoRepository) UpdateSubscriptionPlan(ctx context.Context, entity Subscription) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(entity.UpdatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo subscription updated at")
+	}
+
+	return d.updateSubscription(ctx, entity, &types.Update{
+		UpdateExpression: aws.String("SET #plan = :plan, #interval = :interval, UpdatedAt = :updatedAt"),
+		ExpressionAttributeNames: map[string]string{
+			"#plan":     "Plan",
+			"#interval": "Interval",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":plan":      &types.AttributeValueMemberS{Value: entity.Plan},
+			":interval":  &types.AttributeValueMemberS{Value: entity.Interval},
+			":updatedAt": updatedAt,
+		},
+	})
+}
+
+// UpdateSubscriptionQuantity writes the quantity of the subscription
+func (d *dynamoRepository) UpdateSubscriptionQuantity(ctx context.Context, entity Subscription) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(entity.UpdatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo subscription updated at")
+	}
+
+	return d.updateSubscription(ctx, entity, &types.Update{
+		UpdateExpression: aws.String("SET Quantity = :quantity, UpdatedAt = :updatedAt"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":quantity":  &types.AttributeValueMemberN{Value: strconv.Itoa(entity.Quantity)},
+			":updatedAt": updatedAt,
+		},
+	})
+}
+
+// UpdateSubscriptionAddOns writes the add-ons of the subscription
+func (d *dynamoRepository) UpdateSubscriptionAddOns(ctx context.Context, entity Subscription) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	updatedAt, err := attributevalue.Marshal(entity.UpdatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo subscription updated at")
+	}
+	addOns, err := attributevalue.Marshal(entity.AddOns)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal dynamo subscription add-ons")
+	}
+
+	return d.updateSubscription(ctx, entity, &types.Update{
+		UpdateExpression: aws.String("SET AddOns = :addOns, UpdatedAt = :updatedAt"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":addOns":    addOns,
+			":updatedAt": updatedAt,
+		},
+	})
+}
+
+// GetStatusHistory returns the status changes of a subscription, the oldest first
+func (d *dynamoRepository) GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]StatusHistory, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.queryTimeout)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("CUSTOMER#%s", customerId)},
+			":sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("HISTORY#%s#", subscriptionId)},
+		},
+	}
+
+	var entities []StatusHistory
+	paginator := dynamodb.NewQueryPaginator(d.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query dynamo status history entities")
+		}
+		var pageEntities []StatusHistory
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &pageEntities); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal dynamo status history entities")
+		}
+		entities = append(entities, pageEntities...)
+	}
+
+	return entities, nil
+}
+
+// updateSubscription runs a single update of the subscription item. Without a condition of its own the
+// update only requires the subscription to exist.
+func (d *dynamoRepository) updateSubscription(ctx context.Context, entity Subscription, update *types.Update) error {
+	condition := update.ConditionExpression
+	if condition == nil {
+		condition = aws.String("attribute_exists(PK)")
+	}
+
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("CUSTOMER#%s", entity.CustomerId)},
+			"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("SUBSCRIPTION#%s", entity.SubscriptionId)},
+		},
+		TableName:                           aws.String(d.table),
+		UpdateExpression:                    update.UpdateExpression,
+		ConditionExpression:                 condition,
+		ExpressionAttributeNames:            update.ExpressionAttributeNames,
+		ExpressionAttributeValues:           update.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return conditionFailedErr(entity, conditionErr.Item)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to update dynamo subscription entity")
+	}
+	return nil
+}
+
+// conditionFailedErr tells a missing subscription from a stale update, the old item is only
+// returned if it exists
+func conditionFailedErr(entity Subscription, item map[string]types.AttributeValue) error {
+	if item == nil {
+		return model.NewSubscriptionNotFoundErr(entity.SubscriptionId)
+	}
+	return model.NewStaleSubscriptionUpdateErr(entity.SubscriptionId)
+}
+
+// History items are stored next to the subscription, the SK does not start with SUBSCRIPTION# so they
+// are not returned with the subscriptions of the customer
+func statusHistoryKey(subscriptionId string, changedAt time.Time) string {
+	return fmt.Sprintf("HISTORY#%s#%s", subscriptionId, changedAt.UTC().Format(historyTimeFormat))
+}
+
+// findExternalId returns the item of the provider id from the GSI1 index. Items stored before the index
+// have no GSI1 keys, they are scanned for by their attribute instead and get the keys written on the way,
+// so they are found through the index from then on.
+func (d *dynamoRepository) findExternalId(ctx context.Context, gsi1pk, skPrefix, attribute, externalId string) (map[string]types.AttributeValue, error) {
+	result, err := d.queryExternalId(ctx, gsi1pk)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Items) > 0 {
+		return result.Items[0], nil
+	}
+
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:        aws.String(d.table),
+		FilterExpression: aws.String("begins_with(SK, :sk) AND #externalId = :externalId AND attribute_not_exists(GSI1PK)"),
+		ExpressionAttributeNames: map[string]string{
+			"#externalId": attribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sk":         &types.AttributeValueMemberS{Value: skPrefix},
+			":externalId": &types.AttributeValueMemberS{Value: externalId},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Items) == 0 {
+			continue
+		}
+		item := page.Items[0]
+		_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			Key: map[string]types.AttributeValue{
+				"PK": item["PK"],
+				"SK": item["SK"],
+			},
+			TableName:           aws.String(d.table),
+			UpdateExpression:    aws.String("SET GSI1PK = :gsi1pk, GSI1SK = SK"),
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":gsi1pk": &types.AttributeValueMemberS{Value: gsi1pk},
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write the external id keys")
+		}
+		return item, nil
+	}
+	return nil, nil
+}
+
+func (d *dynamoRepository) queryExternalId(ctx context.Context, gsi1pk string) (*dynamodb.QueryOutput, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		IndexName:              aws.String(externalIdIndex),
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: gsi1pk},
+		},
+		Limit: aws.Int32(1),
+	}
+	return d.client.Query(ctx, input)
+}
+
 func unmarshalCustomerEntity(result *dynamodb.GetItemOutput) (*Customer, error) {
 	if result.Item == nil {
 		return nil, nil
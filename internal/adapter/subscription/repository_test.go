@@ -9,9 +9,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DenisBarabanshchikov/subscription/internal/adapter/subscription"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
 )
 
 func TestDynamoRepository_CreateAndGetCustomer(t *testing.T) {
@@ -38,6 +43,37 @@ func TestDynamoRepository_CreateAndGetCustomer(t *testing.T) {
 	assert.NotNil(t, retrieved, "customer not found")
 	assert.Equal(t, cust.CustomerId, retrieved.CustomerId)
 	assert.Equal(t, cust.ExternalCustomerId, retrieved.ExternalCustomerId)
+	assert.Empty(t, retrieved.Currency)
+}
+
+func TestDynamoRepository_SetCustomerCurrency(t *testing.T) {
+	repo := subscription.NewDynamoRepository(config.ProvideSubscriptionDynamoConfig())
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	ctx := context.Background()
+	err := repo.CreateCustomer(ctx, subscription.Customer{
+		CustomerId:         customerId,
+		ExternalCustomerId: "external-" + customerId,
+		CreatedAt:          time.Now().UTC(),
+		UpdatedAt:          time.Now().UTC(),
+	})
+	assert.NoError(t, err, "failed to create customer")
+
+	err = repo.SetCustomerCurrency(ctx, customerId, "eur")
+	assert.NoError(t, err, "failed to set currency")
+
+	// The same currency again is fine, another one is rejected
+	err = repo.SetCustomerCurrency(ctx, customerId, "eur")
+	assert.NoError(t, err)
+	err = repo.SetCustomerCurrency(ctx, customerId, "usd")
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
+
+	retrieved, err := repo.GetCustomer(ctx, customerId)
+	assert.NoError(t, err, "failed to get customer")
+	assert.Equal(t, "eur", retrieved.Currency)
+
+	err = repo.SetCustomerCurrency(ctx, "missing-"+customerId, "eur")
+	assert.IsType(t, model.CustomerNotFoundErr{}, err)
 }
 
 func TestDynamoRepository_CreateAndGetSubscription(t *testing.T) {
@@ -57,12 +93,15 @@ func TestDynamoRepository_CreateAndGetSubscription(t *testing.T) {
 
 	// Create a unique test subscription.
 	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	trialEnd := time.Now().UTC().AddDate(0, 0, 14).Truncate(time.Second)
 	sub := subscription.Subscription{
-		SubscriptionId: subscriptionId,
-		CustomerId:     customerId,
-		Status:         "incomplete",
-		CreatedAt:      time.Now().UTC(),
-		UpdatedAt:      time.Now().UTC(),
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Status:                 "trialing",
+		TrialEnd:               &trialEnd,
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
 	}
 
 	// Insert the subscription.
@@ -76,4 +115,298 @@ func TestDynamoRepository_CreateAndGetSubscription(t *testing.T) {
 	assert.Equal(t, sub.SubscriptionId, retrievedSub.SubscriptionId)
 	assert.Equal(t, sub.CustomerId, retrievedSub.CustomerId)
 	assert.Equal(t, sub.Status, retrievedSub.Status)
+	assert.True(t, trialEnd.Equal(*retrievedSub.TrialEnd))
+
+	// A second subscription of the same provider subscription is rejected.
+	duplicate := sub
+	duplicate.SubscriptionId = subscriptionId + "-duplicate"
+	err = repo.CreateSubscription(ctx, duplicate)
+	assert.IsType(t, model.SubscriptionAlreadyExistsErr{}, err)
+
+	retrievedSub, err = repo.GetSubscription(ctx, customerId, duplicate.SubscriptionId)
+	assert.NoError(t, err, "failed to get subscription")
+	assert.Nil(t, retrievedSub)
+}
+
+// TestDynamoRepository_GetByExternalIdWithoutIndexKeys finds a subscription stored before the GSI1 keys
+// were written, and the lookup writes them
+func TestDynamoRepository_GetByExternalIdWithoutIndexKeys(t *testing.T) {
+	cfg := config.ProvideSubscriptionDynamoConfig()
+	repo := subscription.NewDynamoRepository(cfg)
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	item, err := attributevalue.MarshalMap(subscription.Subscription{
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Status:                 "active",
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	})
+	assert.NoError(t, err, "failed to marshal subscription")
+	item["PK"] = &types.AttributeValueMemberS{Value: "CUSTOMER#" + customerId}
+	item["SK"] = &types.AttributeValueMemberS{Value: "SUBSCRIPTION#" + subscriptionId}
+
+	ctx := context.Background()
+	_, err = cfg.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(cfg.Table), Item: item})
+	assert.NoError(t, err, "failed to put subscription without GSI1 keys")
+
+	retrieved, err := repo.GetSubscriptionByExternalId(ctx, "external-"+subscriptionId)
+	assert.NoError(t, err, "failed to get subscription by external id")
+	if assert.NotNil(t, retrieved, "subscription not found") {
+		assert.Equal(t, subscriptionId, retrieved.SubscriptionId)
+	}
+
+	stored, err := cfg.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(cfg.Table),
+		Key:       map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+	})
+	assert.NoError(t, err, "failed to get subscription item")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "EXTERNAL_SUBSCRIPTION#external-" + subscriptionId}, stored.Item["GSI1PK"])
+	assert.Equal(t, item["SK"], stored.Item["GSI1SK"])
+
+	// An unknown id is still not found.
+	retrieved, err = repo.GetSubscriptionByExternalId(ctx, "external-missing")
+	assert.NoError(t, err, "failed to get subscription by external id")
+	assert.Nil(t, retrieved)
+}
+
+func TestDynamoRepository_GetByExternalIdAndUpdateSubscription(t *testing.T) {
+	repo := subscription.NewDynamoRepository(config.ProvideSubscriptionDynamoConfig())
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	cust := subscription.Customer{
+		CustomerId:         customerId,
+		ExternalCustomerId: "external-" + customerId,
+		CreatedAt:          time.Now().UTC(),
+		UpdatedAt:          time.Now().UTC(),
+	}
+	ctx := context.Background()
+	err := repo.CreateCustomer(ctx, cust)
+	assert.NoError(t, err, "failed to create customer for subscription")
+
+	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	sub := subscription.Subscription{
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Status:                 "incomplete",
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	}
+	err = repo.CreateSubscription(ctx, sub)
+	assert.NoError(t, err, "failed to create subscription")
+
+	// Resolve the customer by the provider id.
+	retrievedCust, err := repo.GetCustomerByExternalId(ctx, cust.ExternalCustomerId)
+	assert.NoError(t, err, "failed to get customer by external id")
+	assert.NotNil(t, retrievedCust, "customer not found")
+	assert.Equal(t, customerId, retrievedCust.CustomerId)
+
+	// Update the status of the subscription resolved by the provider id.
+	retrievedSub, err := repo.GetSubscriptionByExternalId(ctx, sub.ExternalSubscriptionID)
+	assert.NoError(t, err, "failed to get subscription by external id")
+	assert.NotNil(t, retrievedSub, "subscription not found")
+
+	retrievedSub.Plan = "Growth"
+	retrievedSub.Interval = "year"
+	retrievedSub.Quantity = 3
+	retrievedSub.AddOns = []subscription.AddOn{{Name: "ExtraStorage", ExternalItemId: "si_123", Quantity: 2}}
+	retrievedSub.Status = "active"
+	retrievedSub.CancelAtPeriodEnd = true
+	retrievedSub.UpdatedAt = time.Now().UTC()
+	err = repo.UpdateSubscriptionStatus(ctx, *retrievedSub, nil)
+	assert.NoError(t, err, "failed to update subscription status")
+	err = repo.UpdateSubscriptionPlan(ctx, *retrievedSub)
+	assert.NoError(t, err, "failed to update subscription plan")
+	err = repo.UpdateSubscriptionQuantity(ctx, *retrievedSub)
+	assert.NoError(t, err, "failed to update subscription quantity")
+	err = repo.UpdateSubscriptionAddOns(ctx, *retrievedSub)
+	assert.NoError(t, err, "failed to update subscription add-ons")
+
+	subs, err := repo.GetSubscriptions(ctx, customerId)
+	assert.NoError(t, err, "failed to get subscriptions")
+	assert.Len(t, subs, 1)
+	assert.Equal(t, "active", subs[0].Status)
+	assert.Equal(t, "Growth", subs[0].Plan)
+	assert.Equal(t, "year", subs[0].Interval)
+	assert.Equal(t, 3, subs[0].Quantity)
+	assert.Equal(t, retrievedSub.AddOns, subs[0].AddOns)
+	assert.True(t, subs[0].CancelAtPeriodEnd)
+
+	// Updating a missing subscription fails instead of creating it.
+	err = repo.UpdateSubscriptionStatus(ctx, subscription.Subscription{CustomerId: customerId, SubscriptionId: "missing"}, nil)
+	assert.IsType(t, model.SubscriptionNotFoundErr{}, err)
+	err = repo.UpdateSubscriptionAddOns(ctx, subscription.Subscription{CustomerId: customerId, SubscriptionId: "missing"})
+	assert.IsType(t, model.SubscriptionNotFoundErr{}, err)
+}
+
+// TestDynamoRepository_UpdatesKeepEachOther checks that a status sync and an attribute update of a
+// subscription read before the other write don't overwrite each other, in both orders
+func TestDynamoRepository_UpdatesKeepEachOther(t *testing.T) {
+	repo := subscription.NewDynamoRepository(config.ProvideSubscriptionDynamoConfig())
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	sub := subscription.Subscription{
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Plan:                   "Core",
+		Quantity:               1,
+		Status:                 "active",
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	}
+	ctx := context.Background()
+	err := repo.CreateSubscription(ctx, sub)
+	assert.NoError(t, err, "failed to create subscription")
+
+	// The webhook syncs past_due while the API changes the plan of the copy read before.
+	synced := sub
+	syncedAt := time.Now().UTC()
+	synced.Status = "past_due"
+	synced.LastEventAt = &syncedAt
+	err = repo.UpdateSubscriptionStatus(ctx, synced, nil)
+	assert.NoError(t, err, "failed to update subscription status")
+
+	changed := sub
+	changed.Plan = "Growth"
+	err = repo.UpdateSubscriptionPlan(ctx, changed)
+	assert.NoError(t, err, "failed to update subscription plan")
+
+	retrieved, err := repo.GetSubscription(ctx, customerId, subscriptionId)
+	assert.NoError(t, err, "failed to get subscription")
+	assert.Equal(t, "past_due", retrieved.Status)
+	assert.Equal(t, "Growth", retrieved.Plan)
+
+	// The API changes the quantity while the webhook syncs active onto the copy read before.
+	changed = *retrieved
+	changed.Quantity = 5
+	err = repo.UpdateSubscriptionQuantity(ctx, changed)
+	assert.NoError(t, err, "failed to update subscription quantity")
+
+	syncedAt = syncedAt.Add(time.Second)
+	synced.Status = "active"
+	synced.LastEventAt = &syncedAt
+	err = repo.UpdateSubscriptionStatus(ctx, synced, nil)
+	assert.NoError(t, err, "failed to update subscription status")
+
+	retrieved, err = repo.GetSubscription(ctx, customerId, subscriptionId)
+	assert.NoError(t, err, "failed to get subscription")
+	assert.Equal(t, "active", retrieved.Status)
+	assert.Equal(t, "Growth", retrieved.Plan)
+	assert.Equal(t, 5, retrieved.Quantity)
+}
+
+func TestDynamoRepository_UpdateSubscriptionStatusRejectsOlderEvent(t *testing.T) {
+	repo := subscription.NewDynamoRepository(config.ProvideSubscriptionDynamoConfig())
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	sub := subscription.Subscription{
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Status:                 "incomplete",
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	}
+	ctx := context.Background()
+	err := repo.CreateSubscription(ctx, sub)
+	assert.NoError(t, err, "failed to create subscription")
+
+	// Sync from a newer event first.
+	newer := time.Now().UTC()
+	sub.Status = "active"
+	sub.LastEventAt = &newer
+	err = repo.UpdateSubscriptionStatus(ctx, sub, nil)
+	assert.NoError(t, err, "failed to update subscription from newer event")
+
+	// An older event delivered later must not downgrade the status.
+	older := newer.Add(-time.Minute)
+	sub.Status = "incomplete"
+	sub.LastEventAt = &older
+	err = repo.UpdateSubscriptionStatus(ctx, sub, nil)
+	assert.IsType(t, model.StaleSubscriptionUpdateErr{}, err)
+
+	retrieved, err := repo.GetSubscription(ctx, customerId, subscriptionId)
+	assert.NoError(t, err, "failed to get subscription")
+	assert.Equal(t, "active", retrieved.Status)
+	assert.Equal(t, newer.Unix(), retrieved.LastEventAt.Unix())
+
+	// A status read from the provider records when it was synced and keeps the time of the last event.
+	syncedAt := time.Now().UTC()
+	synced := *retrieved
+	synced.Status = "paused"
+	synced.SyncedAt = &syncedAt
+	err = repo.UpdateSubscriptionStatus(ctx, synced, nil)
+	assert.NoError(t, err, "failed to update subscription from provider status")
+
+	retrieved, err = repo.GetSubscription(ctx, customerId, subscriptionId)
+	assert.NoError(t, err, "failed to get subscription")
+	assert.Equal(t, "paused", retrieved.Status)
+	assert.Equal(t, newer.Unix(), retrieved.LastEventAt.Unix())
+	assert.True(t, syncedAt.Equal(*retrieved.SyncedAt))
+}
+
+func TestDynamoRepository_StatusHistory(t *testing.T) {
+	repo := subscription.NewDynamoRepository(config.ProvideSubscriptionDynamoConfig())
+
+	customerId := fmt.Sprintf("testcust-%d", time.Now().UnixNano())
+	subscriptionId := fmt.Sprintf("testsub-%d", time.Now().UnixNano())
+	sub := subscription.Subscription{
+		SubscriptionId:         subscriptionId,
+		CustomerId:             customerId,
+		ExternalSubscriptionID: "external-" + subscriptionId,
+		Status:                 "incomplete",
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	}
+	ctx := context.Background()
+	err := repo.CreateSubscription(ctx, sub)
+	assert.NoError(t, err, "failed to create subscription")
+
+	// Two status changes, each written together with its history item.
+	for _, change := range [][2]string{{"incomplete", "active"}, {"active", "past_due"}} {
+		sub.Status = change[1]
+		sub.UpdatedAt = time.Now().UTC()
+		err = repo.UpdateSubscriptionStatus(ctx, sub, &subscription.StatusHistory{
+			SubscriptionId: subscriptionId,
+			CustomerId:     customerId,
+			PreviousStatus: change[0],
+			Status:         change[1],
+			Source:         "webhook",
+			EventId:        "evt_" + change[1],
+			ChangedAt:      time.Now().UTC(),
+		})
+		assert.NoError(t, err, "failed to update subscription")
+	}
+
+	history, err := repo.GetStatusHistory(ctx, customerId, subscriptionId)
+	assert.NoError(t, err, "failed to get status history")
+	assert.Len(t, history, 2)
+	// Oldest first.
+	assert.Equal(t, "active", history[0].Status)
+	assert.Equal(t, "past_due", history[1].Status)
+	assert.Equal(t, "evt_past_due", history[1].EventId)
+
+	// History items are not returned as subscriptions.
+	subs, err := repo.GetSubscriptions(ctx, customerId)
+	assert.NoError(t, err, "failed to get subscriptions")
+	assert.Len(t, subs, 1)
+	assert.Equal(t, "past_due", subs[0].Status)
+
+	// A failed update doesn't write a history item.
+	err = repo.UpdateSubscriptionStatus(ctx, subscription.Subscription{CustomerId: customerId, SubscriptionId: "missing"}, &subscription.StatusHistory{
+		SubscriptionId: "missing",
+		CustomerId:     customerId,
+		Status:         "active",
+		ChangedAt:      time.Now().UTC(),
+	})
+	assert.IsType(t, model.SubscriptionNotFoundErr{}, err)
+	history, err = repo.GetStatusHistory(ctx, customerId, "missing")
+	assert.NoError(t, err, "failed to get status history")
+	assert.Empty(t, history)
 }
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"crypto/subtle"
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type AdminConfig struct {
+	Token string
+}
+
+type AdminMiddleware struct {
+	token string
+}
+
+func NewAdminMiddleware(config AdminConfig) *AdminMiddleware {
+	return &AdminMiddleware{
+		token: config.Token,
+	}
+}
+
+// Authorize aborts the request unless it carries the admin token as a bearer token
+func (m *AdminMiddleware) Authorize(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) != 1 {
+		res := response.ErrorResponse{Code: http.StatusUnauthorized, Message: "invalid admin token"}
+		c.AbortWithStatusJSON(res.Code, res)
+		return
+	}
+
+	c.Next()
+}
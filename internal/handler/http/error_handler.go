@@ -9,8 +9,12 @@ import (
 
 func handleError(ctx context.Context, err error) response.ErrorResponse {
 	switch e := err.(type) {
-	case model.CustomerNotFoundErr, model.SubscriptionNotFoundErr:
+	case model.CustomerNotFoundErr, model.SubscriptionNotFoundErr, model.EventNotFoundErr, model.AddOnNotFoundErr, model.PaymentMethodNotFoundErr, model.PlanNotFoundErr:
 		return response.ErrorResponse{Code: http.StatusNotFound, Message: e.Error()}
+	case model.InvalidWebhookErr, model.ValidationErr:
+		return response.ErrorResponse{Code: http.StatusBadRequest, Message: e.Error()}
+	case model.IllegalStatusTransitionErr, model.SubscriptionEndedErr, model.PlanAlreadyExistsErr, model.StalePlanUpdateErr, model.SubscriptionAlreadyExistsErr, model.CurrencyMismatchErr:
+		return response.ErrorResponse{Code: http.StatusConflict, Message: e.Error()}
 	default:
 		return response.ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()}
 	}
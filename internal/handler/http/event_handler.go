@@ -0,0 +1,138 @@
+package http
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/request"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type EventHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewEventHandler(webhookService service.WebhookService) *EventHandler {
+	return &EventHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ListEvents handles the list webhook events request.
+// @Description  List received webhook events, newest first (defaults to the last 24 hours)
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        type    query     string  false  "Event type, e.g. invoice.paid"
+// @Param        status  query     string  false  "Processing status (processing, processed, failed, ignored, manual_review)"
+// @Param        from    query     string  false  "Created from (RFC3339)"
+// @Param        to      query     string  false  "Created to (RFC3339)"
+// @Param        limit   query     int     false  "Max number of events (default 100, max 1000)"
+// @Success      200  {object}  response.Events
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/events [get]
+func (h *EventHandler) ListEvents(c *gin.Context) {
+	var req request.ListEvents
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	events, err := h.webhookService.ListEvents(ctx, mapToListEventsFilter(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToEventsResponse(events))
+}
+
+// GetEvent handles the get webhook event request.
+// @Description  Get a received webhook event with its payload and processing result
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        eventId    path      string  true  "eventId"
+// @Success      200  {object}  response.EventDetails
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/events/{eventId} [get]
+func (h *EventHandler) GetEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	ctx := c.Request.Context()
+
+	event, err := h.webhookService.GetEvent(ctx, eventId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToEventDetailsResponse(event))
+}
+
+// ReplayEvent handles the replay webhook event request.
+// @Description  Process a received webhook event again
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        eventId    path      string  true  "eventId"
+// @Success      200  {object}  response.Event
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/events/{eventId}/replay [post]
+func (h *EventHandler) ReplayEvent(c *gin.Context) {
+	eventId := c.Param("eventId")
+
+	ctx := c.Request.Context()
+
+	event, err := h.webhookService.ReplayEvent(ctx, eventId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToEventResponse(event))
+}
+
+// ReplayEvents handles the replay webhook events request.
+// @Description  Process again all received webhook events of a time window (only failed ones unless a status is given)
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body  request.ReplayEvents  true  "Events to replay"
+// @Success      200  {object}  response.Events
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/events/replay [post]
+func (h *EventHandler) ReplayEvents(c *gin.Context) {
+	var req request.ReplayEvents
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	events, err := h.webhookService.ReplayEvents(ctx, mapToReplayEventsFilter(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToEventsResponse(events))
+}
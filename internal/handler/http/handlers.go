@@ -2,12 +2,24 @@ package http
 
 type Handlers struct {
 	SubscriptionHandler *SubscriptionHandler
+	WebhookHandler      *WebhookHandler
+	EventHandler        *EventHandler
+	PlanHandler         *PlanHandler
+	AdminMiddleware     *AdminMiddleware
 }
 
 func NewHandlers(
 	subscriptionHandler *SubscriptionHandler,
+	webhookHandler *WebhookHandler,
+	eventHandler *EventHandler,
+	planHandler *PlanHandler,
+	adminMiddleware *AdminMiddleware,
 ) *Handlers {
 	return &Handlers{
 		SubscriptionHandler: subscriptionHandler,
+		WebhookHandler:      webhookHandler,
+		EventHandler:        eventHandler,
+		PlanHandler:         planHandler,
+		AdminMiddleware:     adminMiddleware,
 	}
 }
@@ -1,8 +1,16 @@
 package http
 
 import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/request"
 	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/response"
 	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"time"
+)
+
+const (
+	defaultEventsLimit  = 100
+	maxEventsLimit      = 1000
+	defaultEventsPeriod = 24 * time.Hour
 )
 
 func mapToCreateCustomerResponse(customer model.Customer) response.CreateCustomer {
@@ -13,10 +21,86 @@ func mapToCreateCustomerResponse(customer model.Customer) response.CreateCustome
 }
 
 func mapToSubscriberCustomerResponse(subscription model.Subscription) response.SubscribeCustomer {
-	return response.SubscribeCustomer{
+	res := response.SubscribeCustomer{
 		SubscriptionId:         subscription.SubscriptionId,
 		ExternalSubscriptionId: subscription.ExternalSubscriptionID,
+		Status:                 string(subscription.Status),
+		TrialEnd:               subscription.TrialEnd,
+	}
+	if subscription.Payment != nil {
+		payment := mapToPaymentConfirmationResponse(*subscription.Payment)
+		res.Payment = &payment
+	}
+	return res
+}
+
+func mapToPaymentConfirmationResponse(payment model.PaymentConfirmation) response.PaymentConfirmation {
+	return response.PaymentConfirmation{
+		Intent:       string(payment.Intent),
+		ClientSecret: payment.ClientSecret,
+		Status:       payment.Status,
+		NextAction:   payment.NextAction,
+		RedirectUrl:  payment.RedirectUrl,
+	}
+}
+
+func mapToPaymentMethodsResponse(methods []model.PaymentMethod) response.PaymentMethods {
+	res := response.PaymentMethods{PaymentMethods: make([]response.PaymentMethod, 0, len(methods))}
+	for _, method := range methods {
+		res.PaymentMethods = append(res.PaymentMethods, response.PaymentMethod{
+			PaymentMethodId: method.ExternalPaymentMethodId,
+			Type:            method.Type,
+			Brand:           method.Brand,
+			Last4:           method.Last4,
+			ExpMonth:        method.ExpMonth,
+			ExpYear:         method.ExpYear,
+			Default:         method.Default,
+		})
+	}
+	return res
+}
+
+// mapToBillingInterval bills monthly unless another interval is asked for
+func mapToBillingInterval(interval string) model.BillingInterval {
+	if interval == "" {
+		return model.BillingIntervalMonth
 	}
+	return model.BillingInterval(interval)
+}
+
+func mapToCheckoutModel(req request.CreateCheckoutSession) model.Checkout {
+	return model.Checkout{
+		Plan:       req.Plan,
+		Interval:   mapToBillingInterval(req.Interval),
+		Currency:   req.Currency,
+		SuccessUrl: req.SuccessUrl,
+		CancelUrl:  req.CancelUrl,
+	}
+}
+
+func mapToCheckoutSessionResponse(session model.CheckoutSession) response.CheckoutSession {
+	return response.CheckoutSession{
+		SessionId: session.ExternalSessionId,
+		Url:       session.Url,
+	}
+}
+
+func mapToPortalSessionResponse(url string) response.PortalSession {
+	return response.PortalSession{Url: url}
+}
+
+func mapToTrialModel(req request.SubscribeCustomer) *model.Trial {
+	if req.TrialDays == nil && !req.TrialFromPlan && req.TrialEndBehavior == "" {
+		return nil
+	}
+	trial := &model.Trial{
+		PlanDefault: req.TrialFromPlan,
+		EndBehavior: model.TrialEndBehavior(req.TrialEndBehavior),
+	}
+	if req.TrialDays != nil {
+		trial.Days = *req.TrialDays
+	}
+	return trial
 }
 
 func mapToSubscriptionStatusResponse(subscription model.Subscription) response.SubscriptionStatus {
@@ -24,6 +108,242 @@ func mapToSubscriptionStatusResponse(subscription model.Subscription) response.S
 		SubscriptionId:         subscription.SubscriptionId,
 		ExternalSubscriptionID: subscription.ExternalSubscriptionID,
 		Plan:                   subscription.Plan,
-		Status:                 subscription.Status,
+		Interval:               string(subscription.Interval),
+		Currency:               subscription.Currency,
+		Quantity:               subscription.Quantity,
+		AddOns:                 mapToAddOnsResponse(subscription.AddOns).AddOns,
+		Status:                 string(subscription.Status),
+		CancelAtPeriodEnd:      subscription.CancelAtPeriodEnd,
+		TrialEnd:               subscription.TrialEnd,
+	}
+}
+
+func mapToAddOnsResponse(addOns []model.AddOn) response.AddOns {
+	res := response.AddOns{AddOns: make([]response.AddOn, 0, len(addOns))}
+	for _, addOn := range addOns {
+		res.AddOns = append(res.AddOns, response.AddOn{
+			AddOn:    addOn.Name,
+			Quantity: addOn.Quantity,
+		})
+	}
+	return res
+}
+
+func mapToSubscriptionHistoryResponse(history []model.StatusChange) response.SubscriptionHistory {
+	res := response.SubscriptionHistory{Changes: make([]response.StatusChange, 0, len(history))}
+	for _, change := range history {
+		res.Changes = append(res.Changes, response.StatusChange{
+			PreviousStatus: string(change.PreviousStatus),
+			Status:         string(change.Status),
+			Source:         string(change.Source),
+			EventId:        change.EventId,
+			ChangedAt:      change.ChangedAt,
+		})
+	}
+	return res
+}
+
+func mapToInvoicePreviewResponse(preview model.InvoicePreview) response.InvoicePreview {
+	res := response.InvoicePreview{
+		Currency:       preview.Currency,
+		Total:          preview.Total,
+		ProratedAmount: preview.ProratedAmount,
+		Lines:          make([]response.InvoiceLine, 0, len(preview.Lines)),
+	}
+	for _, line := range preview.Lines {
+		res.Lines = append(res.Lines, response.InvoiceLine{
+			Description: line.Description,
+			Amount:      line.Amount,
+			Proration:   line.Proration,
+			PeriodStart: line.PeriodStart,
+			PeriodEnd:   line.PeriodEnd,
+		})
+	}
+	return res
+}
+
+func mapToCancellationModel(req request.CancelSubscription) model.Cancellation {
+	cancellation := model.Cancellation{
+		Mode:   model.CancelMode(req.Mode),
+		Reason: req.Reason,
+	}
+	if cancellation.Mode == "" {
+		cancellation.Mode = model.CancelModeImmediately
+	}
+	return cancellation
+}
+
+func mapToQuantityChangeModel(req request.ChangeQuantity) model.QuantityChange {
+	change := model.QuantityChange{
+		Quantity:          req.Quantity,
+		ProrationBehavior: model.ProrationBehavior(req.ProrationBehavior),
+	}
+	if change.ProrationBehavior == "" {
+		change.ProrationBehavior = model.ProrationBehaviorCreateProrations
+	}
+	return change
+}
+
+func mapToPauseModel(req request.PauseSubscription) model.Pause {
+	pause := model.Pause{
+		ResumesAt: req.ResumesAt,
+		Behavior:  model.PauseBehavior(req.Behavior),
+	}
+	if pause.Behavior == "" {
+		pause.Behavior = model.PauseBehaviorKeepAsDraft
+	}
+	return pause
+}
+
+func mapToPlanChangeModel(req request.ChangePlan) model.PlanChange {
+	change := model.PlanChange{
+		Plan:              req.Plan,
+		Interval:          model.BillingInterval(req.Interval),
+		ProrationBehavior: model.ProrationBehavior(req.ProrationBehavior),
+	}
+	if change.ProrationBehavior == "" {
+		change.ProrationBehavior = model.ProrationBehaviorCreateProrations
+	}
+	return change
+}
+
+func mapToListEventsFilter(req request.ListEvents) model.EventFilter {
+	filter := model.EventFilter{
+		Type:   req.Type,
+		Status: model.EventStatus(req.Status),
+		From:   req.From,
+		To:     req.To,
+		Limit:  req.Limit,
+	}
+	if filter.To.IsZero() {
+		filter.To = time.Now()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.Add(-defaultEventsPeriod)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultEventsLimit
+	}
+	if filter.Limit > maxEventsLimit {
+		filter.Limit = maxEventsLimit
+	}
+	return filter
+}
+
+func mapToReplayEventsFilter(req request.ReplayEvents) model.EventFilter {
+	filter := model.EventFilter{
+		Type:   req.Type,
+		Status: model.EventStatus(req.Status),
+		From:   req.From,
+		To:     req.To,
+		Limit:  maxEventsLimit,
+	}
+	if filter.Status == "" {
+		filter.Status = model.EventStatusFailed
+	}
+	return filter
+}
+
+func mapToEventResponse(event model.Event) response.Event {
+	return response.Event{
+		EventId:       event.EventId,
+		Type:          event.Type,
+		Status:        string(event.Status),
+		Error:         event.Error,
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt,
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+func mapToEventsResponse(events []model.Event) response.Events {
+	res := response.Events{Events: make([]response.Event, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, mapToEventResponse(event))
+	}
+	return res
+}
+
+func mapToEventDetailsResponse(event model.Event) response.EventDetails {
+	return response.EventDetails{
+		EventId:       event.EventId,
+		Type:          event.Type,
+		Status:        string(event.Status),
+		Error:         event.Error,
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt,
+		CreatedAt:     event.CreatedAt,
+		Payload:       event.Payload,
+	}
+}
+
+func mapToPlanModel(planId string, req request.Plan) model.Plan {
+	plan := model.Plan{
+		PlanId:    planId,
+		Name:      req.Name,
+		TrialDays: req.TrialDays,
+		Features:  req.Features,
+	}
+	for _, price := range req.Prices {
+		plan.Prices = append(plan.Prices, model.PlanPrice{
+			Interval:         model.BillingInterval(price.Interval),
+			Amount:           price.Amount,
+			Currency:         price.Currency,
+			ExternalPriceIds: price.PriceIds,
+		})
+	}
+	return plan
+}
+
+func mapToPlanResponse(plan model.Plan) response.Plan {
+	res := response.Plan{
+		PlanId:    plan.PlanId,
+		Name:      plan.Name,
+		Prices:    make([]response.PlanPrice, 0, len(plan.Prices)),
+		TrialDays: plan.TrialDays,
+		Features:  plan.Features,
+		Archived:  plan.Archived,
+	}
+	for _, price := range plan.Prices {
+		priceIds := price.ExternalPriceIds
+		if priceIds == nil {
+			priceIds = map[string]string{}
+		}
+		res.Prices = append(res.Prices, response.PlanPrice{
+			Interval: string(price.Interval),
+			Amount:   price.Amount,
+			Currency: price.Currency,
+			PriceIds: priceIds,
+		})
+	}
+	if res.Features == nil {
+		res.Features = []string{}
+	}
+	return res
+}
+
+func mapToPlansResponse(plans []model.Plan) response.Plans {
+	res := response.Plans{Plans: make([]response.Plan, 0, len(plans))}
+	for _, plan := range plans {
+		res.Plans = append(res.Plans, mapToPlanResponse(plan))
+	}
+	return res
+}
+
+func mapToPlanSyncResponse(sync model.PlanSync) response.PlanSync {
+	res := response.PlanSync{
+		Created:     sync.Created,
+		Updated:     sync.Updated,
+		Unavailable: sync.Unavailable,
+	}
+	if res.Created == nil {
+		res.Created = []string{}
+	}
+	if res.Updated == nil {
+		res.Updated = []string{}
+	}
+	if res.Unavailable == nil {
+		res.Unavailable = []string{}
 	}
+	return res
 }
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/request"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PlanHandler struct {
+	planService service.PlanService
+}
+
+func NewPlanHandler(planService service.PlanService) *PlanHandler {
+	return &PlanHandler{
+		planService: planService,
+	}
+}
+
+// GetPlans handles the list plans request.
+// @Description  List the plans which can be subscribed to, cheapest first
+// @Tags         Plan
+// @Produce      json
+// @Success      200  {object}  response.Plans
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/plans [get]
+func (h *PlanHandler) GetPlans(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	plans, err := h.planService.Plans(ctx)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlansResponse(plans))
+}
+
+// GetPlan handles the get plan request.
+// @Description  Get a plan of the catalog, archived plans included
+// @Tags         Plan
+// @Produce      json
+// @Param        planId    path      string  true  "planId"
+// @Success      200  {object}  response.Plan
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/plans/{planId} [get]
+func (h *PlanHandler) GetPlan(c *gin.Context) {
+	planId := c.Param("planId")
+
+	ctx := c.Request.Context()
+
+	plan, err := h.planService.Plan(ctx, planId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlanResponse(plan))
+}
+
+// CreatePlan handles the create plan request.
+// @Description  Add a plan to the catalog
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body  request.CreatePlan  true  "Plan"
+// @Success      200  {object}  response.Plan
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/plans [post]
+func (h *PlanHandler) CreatePlan(c *gin.Context) {
+	var req request.CreatePlan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	plan, err := h.planService.CreatePlan(ctx, mapToPlanModel(req.PlanId, req.Plan))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlanResponse(plan))
+}
+
+// UpdatePlan handles the update plan request.
+// @Description  Replace a plan of the catalog, an archived plan stays archived. A plan changed meanwhile is a conflict
+// @Tags         Admin
+// @Accept       application/json
+// @Produce      json
+// @Security     AdminToken
+// @Param        planId    path      string  true  "planId"
+// @Param        request  body  request.Plan  true  "Plan"
+// @Success      200  {object}  response.Plan
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/plans/{planId} [put]
+func (h *PlanHandler) UpdatePlan(c *gin.Context) {
+	var req request.Plan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	planId := c.Param("planId")
+
+	ctx := c.Request.Context()
+
+	plan, err := h.planService.UpdatePlan(ctx, mapToPlanModel(planId, req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlanResponse(plan))
+}
+
+// ArchivePlan handles the archive plan request.
+// @Description  Take a plan off sale, existing subscriptions keep it
+// @Tags         Admin
+// @Produce      json
+// @Security     AdminToken
+// @Param        planId    path      string  true  "planId"
+// @Success      200  {object}  response.Plan
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/plans/{planId} [delete]
+func (h *PlanHandler) ArchivePlan(c *gin.Context) {
+	planId := c.Param("planId")
+
+	ctx := c.Request.Context()
+
+	plan, err := h.planService.ArchivePlan(ctx, planId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlanResponse(plan))
+}
+
+// SyncPlans handles the sync plans request.
+// @Description  Sync the plan catalog with the products and prices in Stripe right away, plans are matched by the plan in the product metadata
+// @Tags         Admin
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {object}  response.PlanSync
+// @Failure      401  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/admin/plans/sync [post]
+func (h *PlanHandler) SyncPlans(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sync, err := h.planService.SyncPlans(ctx)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPlanSyncResponse(sync))
+}
@@ -1,9 +1,90 @@
 package request
 
+import "time"
+
 type CreateCustomer struct {
 	Email string `json:"email"`
 }
 
+// SubscribeCustomer asks for a trial with either TrialDays or TrialFromPlan. Without a currency the
+// customer is billed in their currency, or in the main currency of the plan on the first subscription.
 type SubscribeCustomer struct {
-	Plan string `json:"plan"`
+	Plan             string `json:"plan"`
+	Interval         string `json:"interval" enums:"month,quarter,year"`
+	Currency         string `json:"currency" example:"eur"`
+	Quantity         int    `json:"quantity"`
+	TrialDays        *int   `json:"trialDays"`
+	TrialFromPlan    bool   `json:"trialFromPlan"`
+	TrialEndBehavior string `json:"trialEndBehavior" enums:"cancel,pause"`
+}
+
+type CreateCheckoutSession struct {
+	Plan       string `json:"plan" binding:"required"`
+	Interval   string `json:"interval" enums:"month,quarter,year"`
+	Currency   string `json:"currency" example:"eur"`
+	SuccessUrl string `json:"successUrl" binding:"required,url"`
+	CancelUrl  string `json:"cancelUrl" binding:"required,url"`
+}
+
+type CancelSubscription struct {
+	Mode   string `form:"mode" enums:"immediately,at_period_end"`
+	Reason string `form:"reason"`
+}
+
+type ChangeQuantity struct {
+	Quantity          int    `json:"quantity" binding:"required"`
+	ProrationBehavior string `json:"prorationBehavior" enums:"create_prorations,none,always_invoice"`
+}
+
+type AddAddOn struct {
+	AddOn    string `json:"addOn" binding:"required" enums:"ExtraStorage,PrioritySupport"`
+	Quantity int    `json:"quantity"`
+}
+
+type PauseSubscription struct {
+	ResumesAt *time.Time `json:"resumesAt"`
+	Behavior  string     `json:"behavior" enums:"keep_as_draft,mark_uncollectible,void"`
+}
+
+// ChangePlan is the body of a plan change and the query of its preview, without an interval the current one is kept
+type ChangePlan struct {
+	Plan              string `json:"plan" form:"plan" binding:"required"`
+	Interval          string `json:"interval" form:"interval" enums:"month,quarter,year"`
+	ProrationBehavior string `json:"prorationBehavior" form:"prorationBehavior" enums:"create_prorations,none,always_invoice"`
+}
+
+type ListEvents struct {
+	Type   string    `form:"type"`
+	Status string    `form:"status"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int       `form:"limit"`
+}
+
+type ReplayEvents struct {
+	Type   string    `json:"type"`
+	Status string    `json:"status"`
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required"`
+}
+
+// Plan is a plan of the catalog with at most one price per billing interval and currency, the first price is in its main currency
+type Plan struct {
+	Name      string      `json:"name" binding:"required"`
+	Prices    []PlanPrice `json:"prices" binding:"dive"`
+	TrialDays int         `json:"trialDays"`
+	Features  []string    `json:"features"`
+}
+
+// PlanPrice is what a seat costs per interval, PriceIds maps an environment such as test or live to the provider price
+type PlanPrice struct {
+	Interval string            `json:"interval" binding:"required" enums:"month,quarter,year"`
+	Amount   int64             `json:"amount"`
+	Currency string            `json:"currency" binding:"required" example:"usd"`
+	PriceIds map[string]string `json:"priceIds"`
+}
+
+type CreatePlan struct {
+	PlanId string `json:"planId" binding:"required"`
+	Plan
 }
@@ -1,18 +1,155 @@
 package response
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type CreateCustomer struct {
 	CustomerId         string `json:"customerId"`
 	ExternalCustomerId string `json:"externalCustomerId"`
 }
 
 type SubscribeCustomer struct {
-	SubscriptionId         string `json:"subscriptionId"`
-	ExternalSubscriptionId string `json:"externalSubscriptionId"`
+	SubscriptionId         string     `json:"subscriptionId"`
+	ExternalSubscriptionId string     `json:"externalSubscriptionId"`
+	Status                 string     `json:"status"`
+	TrialEnd               *time.Time `json:"trialEnd,omitempty"`
+	// Payment is missing when nothing has to be confirmed by the customer
+	Payment *PaymentConfirmation `json:"payment,omitempty"`
+}
+
+// PaymentConfirmation is passed to the provider's client library, a payment intent confirms
+// the first payment and a setup intent collects a payment method during a trial
+type PaymentConfirmation struct {
+	Intent       string `json:"intent" enums:"payment,setup"`
+	ClientSecret string `json:"clientSecret"`
+	Status       string `json:"status"`
+	NextAction   string `json:"nextAction,omitempty" example:"use_stripe_sdk"`
+	RedirectUrl  string `json:"redirectUrl,omitempty"`
+}
+
+type CheckoutSession struct {
+	SessionId string `json:"sessionId"`
+	Url       string `json:"url"`
+}
+
+type PortalSession struct {
+	Url string `json:"url"`
+}
+
+type PaymentMethod struct {
+	PaymentMethodId string `json:"paymentMethodId"`
+	Type            string `json:"type" example:"card"`
+	Brand           string `json:"brand,omitempty" example:"visa"`
+	Last4           string `json:"last4,omitempty"`
+	ExpMonth        int    `json:"expMonth,omitempty"`
+	ExpYear         int    `json:"expYear,omitempty"`
+	Default         bool   `json:"default"`
+}
+
+type PaymentMethods struct {
+	PaymentMethods []PaymentMethod `json:"paymentMethods"`
 }
 
 type SubscriptionStatus struct {
-	SubscriptionId         string `json:"subscriptionId"`
-	ExternalSubscriptionID string `json:"externalSubscriptionId"`
-	Plan                   string `json:"plan"`
-	Status                 string `json:"status"`
+	SubscriptionId         string     `json:"subscriptionId"`
+	ExternalSubscriptionID string     `json:"externalSubscriptionId"`
+	Plan                   string     `json:"plan"`
+	Interval               string     `json:"interval" enums:"month,quarter,year"`
+	Currency               string     `json:"currency,omitempty" example:"eur"`
+	Quantity               int        `json:"quantity"`
+	AddOns                 []AddOn    `json:"addOns"`
+	Status                 string     `json:"status" enums:"incomplete,incomplete_expired,trialing,active,past_due,unpaid,paused,canceled"`
+	CancelAtPeriodEnd      bool       `json:"cancelAtPeriodEnd"`
+	TrialEnd               *time.Time `json:"trialEnd,omitempty"`
+}
+
+type AddOn struct {
+	AddOn    string `json:"addOn"`
+	Quantity int    `json:"quantity"`
+}
+
+type AddOns struct {
+	AddOns []AddOn `json:"addOns"`
+}
+
+type StatusChange struct {
+	PreviousStatus string    `json:"previousStatus"`
+	Status         string    `json:"status"`
+	Source         string    `json:"source" enums:"api,webhook,reconciliation"`
+	EventId        string    `json:"eventId,omitempty"`
+	ChangedAt      time.Time `json:"changedAt"`
+}
+
+type SubscriptionHistory struct {
+	Changes []StatusChange `json:"changes"`
+}
+
+// InvoicePreview amounts are in the smallest currency unit
+type InvoicePreview struct {
+	Currency       string        `json:"currency"`
+	Total          int64         `json:"total"`
+	ProratedAmount int64         `json:"proratedAmount"`
+	Lines          []InvoiceLine `json:"lines"`
+}
+
+type InvoiceLine struct {
+	Description string    `json:"description"`
+	Amount      int64     `json:"amount"`
+	Proration   bool      `json:"proration"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+type Event struct {
+	EventId       string     `json:"eventId"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type Events struct {
+	Events []Event `json:"events"`
+}
+
+type EventDetails struct {
+	EventId       string          `json:"eventId"`
+	Type          string          `json:"type"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// Plan is a plan of the catalog, Amount is the price of a seat per Interval in the smallest currency unit
+type Plan struct {
+	PlanId    string      `json:"planId"`
+	Name      string      `json:"name"`
+	Prices    []PlanPrice `json:"prices"`
+	TrialDays int         `json:"trialDays"`
+	Features  []string    `json:"features"`
+	Archived  bool        `json:"archived"`
+}
+
+type PlanPrice struct {
+	Interval string            `json:"interval" enums:"month,quarter,year"`
+	Amount   int64             `json:"amount"`
+	Currency string            `json:"currency"`
+	PriceIds map[string]string `json:"priceIds"`
+}
+
+type Plans struct {
+	Plans []Plan `json:"plans"`
+}
+
+type PlanSync struct {
+	Created     []string `json:"created"`
+	Updated     []string `json:"updated"`
+	Unavailable []string `json:"unavailable"`
 }
@@ -47,7 +47,7 @@ func (h *SubscriptionHandler) CreateCustomer(c *gin.Context) {
 }
 
 // SubscribeCustomer handles the subscribe customer request.
-// @Description  Subscribe a customer (Available plans: Core, Growth, Premium)
+// @Description  Subscribe a customer to a number of seats (Available plans: see GET /plans, default interval: month, default currency: the one of the customer, default quantity: 1), optionally with a free trial of trialDays or of the plan default. The first subscription sets the currency of the customer
 // @Tags         Customer
 // @Accept       application/json
 // @Produce      json
@@ -55,6 +55,7 @@ func (h *SubscriptionHandler) CreateCustomer(c *gin.Context) {
 // @Param        request  body  request.SubscribeCustomer  true  "Subscription data"
 // @Success      200  {object}  response.SubscribeCustomer
 // @Failure      400  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
 // @Failure      500  {object}  response.ErrorResponse
 // @Router       /api/v1/customers/{customerId}/subscriptions [post]
 func (h *SubscriptionHandler) SubscribeCustomer(c *gin.Context) {
@@ -68,7 +69,12 @@ func (h *SubscriptionHandler) SubscribeCustomer(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	subscription, err := h.subscriptionService.SubscriberCustomer(ctx, customerId, req.Plan)
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	subscription, err := h.subscriptionService.SubscriberCustomer(ctx, customerId, req.Plan, mapToBillingInterval(req.Interval), req.Currency, quantity, mapToTrialModel(req))
 	if err != nil {
 		res := handleError(ctx, err)
 		c.JSON(res.Code, res)
@@ -78,6 +84,164 @@ func (h *SubscriptionHandler) SubscribeCustomer(c *gin.Context) {
 	c.JSON(http.StatusOK, mapToSubscriberCustomerResponse(subscription))
 }
 
+// CreateCheckoutSession handles the create checkout session request.
+// @Description  Create a hosted payment page where the customer subscribes to a plan (Available plans: see GET /plans, default currency: the one of the customer), the subscription is recorded once the checkout is completed
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        request  body  request.CreateCheckoutSession  true  "Checkout data"
+// @Success      200  {object}  response.CheckoutSession
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/checkout-sessions [post]
+func (h *SubscriptionHandler) CreateCheckoutSession(c *gin.Context) {
+	var req request.CreateCheckoutSession
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+
+	ctx := c.Request.Context()
+
+	session, err := h.subscriptionService.CreateCheckoutSession(ctx, customerId, mapToCheckoutModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToCheckoutSessionResponse(session))
+}
+
+// CreatePortalSession handles the create portal session request.
+// @Description  Create a billing portal session where the customer manages payment methods and invoices
+// @Tags         Customer
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Success      200  {object}  response.PortalSession
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/portal-sessions [post]
+func (h *SubscriptionHandler) CreatePortalSession(c *gin.Context) {
+	customerId := c.Param("customerId")
+
+	ctx := c.Request.Context()
+
+	url, err := h.subscriptionService.CreatePortalSession(ctx, customerId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPortalSessionResponse(url))
+}
+
+// CreateSetupIntent handles the create setup intent request.
+// @Description  Create a setup intent whose client secret lets the customer save a payment method, e.g. before subscribing
+// @Tags         Customer
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Success      200  {object}  response.PaymentConfirmation
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/setup-intents [post]
+func (h *SubscriptionHandler) CreateSetupIntent(c *gin.Context) {
+	customerId := c.Param("customerId")
+
+	ctx := c.Request.Context()
+
+	intent, err := h.subscriptionService.CreateSetupIntent(ctx, customerId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPaymentConfirmationResponse(intent))
+}
+
+// GetPaymentMethods handles the list payment methods request.
+// @Description  List the saved payment methods of a customer
+// @Tags         Customer
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Success      200  {object}  response.PaymentMethods
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/payment-methods [get]
+func (h *SubscriptionHandler) GetPaymentMethods(c *gin.Context) {
+	customerId := c.Param("customerId")
+
+	ctx := c.Request.Context()
+
+	methods, err := h.subscriptionService.PaymentMethods(ctx, customerId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPaymentMethodsResponse(methods))
+}
+
+// SetDefaultPaymentMethod handles the set default payment method request.
+// @Description  Make a saved payment method the default one for subscriptions and invoices of the customer
+// @Tags         Customer
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        paymentMethodId    path      string  true  "paymentMethodId"
+// @Success      200  {object}  response.PaymentMethods
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/payment-methods/{paymentMethodId}/default [post]
+func (h *SubscriptionHandler) SetDefaultPaymentMethod(c *gin.Context) {
+	customerId := c.Param("customerId")
+	paymentMethodId := c.Param("paymentMethodId")
+
+	ctx := c.Request.Context()
+
+	methods, err := h.subscriptionService.SetDefaultPaymentMethod(ctx, customerId, paymentMethodId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPaymentMethodsResponse(methods))
+}
+
+// DetachPaymentMethod handles the detach payment method request.
+// @Description  Remove a saved payment method from a customer
+// @Tags         Customer
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        paymentMethodId    path      string  true  "paymentMethodId"
+// @Success      200  {object}  response.PaymentMethods
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/payment-methods/{paymentMethodId} [delete]
+func (h *SubscriptionHandler) DetachPaymentMethod(c *gin.Context) {
+	customerId := c.Param("customerId")
+	paymentMethodId := c.Param("paymentMethodId")
+
+	ctx := c.Request.Context()
+
+	methods, err := h.subscriptionService.DetachPaymentMethod(ctx, customerId, paymentMethodId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToPaymentMethodsResponse(methods))
+}
+
 // GetSubscriptionStatus handles the get subscription status request.
 // @Description  Get subscription status
 // @Tags         Customer
@@ -105,18 +269,334 @@ func (h *SubscriptionHandler) GetSubscriptionStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
 }
 
-// HandleStripeWebhook handles the stripe webhook.
-// @Description  Handles the stripe webhook
-// @Tags         Stripe
+// GetSubscriptionHistory handles the get subscription status history request.
+// @Description  Get the status changes of a subscription, the oldest first
+// @Tags         Customer
 // @Accept       application/json
 // @Produce      json
-// @Success      202  "Accepted - no content"
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Success      200  {object}  response.SubscriptionHistory
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/history [get]
+func (h *SubscriptionHandler) GetSubscriptionHistory(c *gin.Context) {
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	history, err := h.subscriptionService.SubscriptionHistory(ctx, customerId, subscriptionId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionHistoryResponse(history))
+}
+
+// CancelSubscription handles the cancel subscription request.
+// @Description  Cancel a subscription immediately or at the end of the current period (default mode: immediately)
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  query  request.CancelSubscription  false  "Cancellation"
+// @Success      200  {object}  response.SubscriptionStatus
 // @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
 // @Failure      500  {object}  response.ErrorResponse
-// @Router       /api/v1/stripe/webhook [post]
-func (h *SubscriptionHandler) HandleStripeWebhook(c *gin.Context) {
-	//TODO handle webhooks
-	//Suggestion create separate notification service,
-	// which will add such events in queue, subscription service will read from this queue
-	c.JSON(http.StatusAccepted, nil)
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId} [delete]
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	var req request.CancelSubscription
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.CancelSubscription(ctx, customerId, subscriptionId, mapToCancellationModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// ChangePlan handles the change subscription plan request.
+// @Description  Upgrade or downgrade the plan of a subscription or switch its billing interval (Available plans: see GET /plans, default interval: the current one, default proration: create_prorations)
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  body  request.ChangePlan  true  "Plan change"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId} [patch]
+func (h *SubscriptionHandler) ChangePlan(c *gin.Context) {
+	var req request.ChangePlan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.ChangePlan(ctx, customerId, subscriptionId, mapToPlanChangeModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// PreviewPlanChange handles the plan change preview request.
+// @Description  Preview the next invoice after a plan change, the subscription is not changed
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  query  request.ChangePlan  true  "Plan change"
+// @Success      200  {object}  response.InvoicePreview
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/plan-preview [get]
+func (h *SubscriptionHandler) PreviewPlanChange(c *gin.Context) {
+	var req request.ChangePlan
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	preview, err := h.subscriptionService.PreviewPlanChange(ctx, customerId, subscriptionId, mapToPlanChangeModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToInvoicePreviewResponse(preview))
+}
+
+// PauseSubscription handles the pause subscription request.
+// @Description  Pause the payment collection of a subscription, until resumed or until resumesAt (default behavior: keep_as_draft)
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  body  request.PauseSubscription  true  "Pause"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/pause [post]
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	var req request.PauseSubscription
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.PauseSubscription(ctx, customerId, subscriptionId, mapToPauseModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// ResumeSubscription handles the resume subscription request.
+// @Description  Resume the payment collection of a paused subscription
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/resume [post]
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.ResumeSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// ChangeQuantity handles the change subscription quantity request.
+// @Description  Change the number of seats of a subscription (default proration: create_prorations)
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  body  request.ChangeQuantity  true  "Quantity change"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/quantity [put]
+func (h *SubscriptionHandler) ChangeQuantity(c *gin.Context) {
+	var req request.ChangeQuantity
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.ChangeQuantity(ctx, customerId, subscriptionId, mapToQuantityChangeModel(req))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// GetAddOns handles the list subscription add-ons request.
+// @Description  List the add-ons of a subscription
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Success      200  {object}  response.AddOns
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/add-ons [get]
+func (h *SubscriptionHandler) GetAddOns(c *gin.Context) {
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+
+	ctx := c.Request.Context()
+
+	addOns, err := h.subscriptionService.AddOns(ctx, customerId, subscriptionId)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToAddOnsResponse(addOns))
+}
+
+// AddAddOn handles the add subscription add-on request.
+// @Description  Add an add-on to a subscription (Available add-ons: ExtraStorage, PrioritySupport, default quantity: 1)
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        request  body  request.AddAddOn  true  "Add-on"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/add-ons [post]
+func (h *SubscriptionHandler) AddAddOn(c *gin.Context) {
+	var req request.AddAddOn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.AddAddOn(ctx, customerId, subscriptionId, req.AddOn, quantity)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
+}
+
+// RemoveAddOn handles the remove subscription add-on request.
+// @Description  Remove an add-on from a subscription
+// @Tags         Customer
+// @Accept       application/json
+// @Produce      json
+// @Param        customerId    path      string  true  "customerId"
+// @Param        subscriptionId    path      string  true  "subscriptionId"
+// @Param        addOn    path      string  true  "addOn"
+// @Success      200  {object}  response.SubscriptionStatus
+// @Failure      404  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/customers/{customerId}/subscriptions/{subscriptionId}/add-ons/{addOn} [delete]
+func (h *SubscriptionHandler) RemoveAddOn(c *gin.Context) {
+	customerId := c.Param("customerId")
+	subscriptionId := c.Param("subscriptionId")
+	addOn := c.Param("addOn")
+
+	ctx := c.Request.Context()
+
+	subscription, err := h.subscriptionService.RemoveAddOn(ctx, customerId, subscriptionId, addOn)
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapToSubscriptionStatusResponse(subscription))
 }
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"github.com/DenisBarabanshchikov/subscription/internal/handler/http/response"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+// maxWebhookBodySize keeps the payload storable, events are stored with their payload in one DynamoDB
+// item of at most 400 KB. The rest leaves room for the other attributes of the event, bigger bodies
+// are rejected before verification.
+const maxWebhookBodySize = 256 << 10
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// HandleStripeWebhook handles the stripe webhook.
+// @Description  Verifies the Stripe-Signature header and handles the stripe webhook
+// @Tags         Stripe
+// @Accept       application/json
+// @Produce      json
+// @Param        Stripe-Signature  header  string  true  "Stripe signature"
+// @Success      202  "Accepted - no content"
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      500  {object}  response.ErrorResponse
+// @Router       /api/v1/stripe/webhook [post]
+func (h *WebhookHandler) HandleStripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		res := response.ErrorResponse{Code: http.StatusBadRequest, Message: "failed to read webhook body"}
+		c.JSON(res.Code, res)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	err = h.webhookService.HandleWebhook(ctx, payload, c.GetHeader("Stripe-Signature"))
+	if err != nil {
+		res := handleError(ctx, err)
+		c.JSON(res.Code, res)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, nil)
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"log"
+	"time"
+)
+
+type PlanSyncConfig struct {
+	Interval time.Duration
+}
+
+// PlanSyncWorker keeps the plan catalog in line with the plans sold by the payment provider
+type PlanSyncWorker struct {
+	interval    time.Duration
+	planService service.PlanService
+}
+
+func NewPlanSyncWorker(config PlanSyncConfig, planService service.PlanService) *PlanSyncWorker {
+	return &PlanSyncWorker{
+		interval:    config.Interval,
+		planService: planService,
+	}
+}
+
+// Run syncs the plans right away and then every interval until the context is done
+func (w *PlanSyncWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *PlanSyncWorker) sync(ctx context.Context) {
+	res, err := w.planService.SyncPlans(ctx)
+	if err != nil {
+		log.Printf("failed to sync plans: %v", err)
+		return
+	}
+	if len(res.Created)+len(res.Updated)+len(res.Unavailable) > 0 {
+		log.Printf("synced plans, created: %v, updated: %v, unavailable: %v", res.Created, res.Updated, res.Unavailable)
+	}
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+	"log"
+	"time"
+)
+
+type RetryConfig struct {
+	Interval time.Duration
+}
+
+// RetryWorker retries failed webhook events in the background
+type RetryWorker struct {
+	interval       time.Duration
+	webhookService service.WebhookService
+}
+
+func NewRetryWorker(config RetryConfig, webhookService service.WebhookService) *RetryWorker {
+	return &RetryWorker{
+		interval:       config.Interval,
+		webhookService: webhookService,
+	}
+}
+
+// Run looks for due retries every interval until the context is done
+func (w *RetryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.webhookService.RetryDueEvents(ctx); err != nil {
+				log.Printf("failed to retry webhook events: %v", err)
+			}
+		}
+	}
+}
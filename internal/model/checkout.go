@@ -0,0 +1,18 @@
+package model
+
+// Checkout asks for a payment page hosted by the provider where the customer subscribes to Plan,
+// billed every Interval in Currency. The customer is sent back to SuccessUrl once subscribed and to CancelUrl
+// when leaving the page.
+type Checkout struct {
+	Plan       string
+	Interval   BillingInterval
+	Currency   string
+	SuccessUrl string
+	CancelUrl  string
+}
+
+// CheckoutSession is a created checkout, Url is its payment page
+type CheckoutSession struct {
+	ExternalSessionId string
+	Url               string
+}
@@ -1,6 +1,10 @@
 package model
 
+// Customer is billed in Currency, set by the first subscription of the customer since the payment
+// provider can't bill a customer in more than one currency. Customers who subscribed before
+// currencies were introduced have none.
 type Customer struct {
 	CustomerId         string
 	ExternalCustomerId string
+	Currency           string
 }
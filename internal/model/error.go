@@ -25,3 +25,189 @@ func NewSubscriptionNotFoundErr(subscriptionId string) SubscriptionNotFoundErr {
 func (e SubscriptionNotFoundErr) Error() string {
 	return e.msg
 }
+
+type InvalidWebhookErr struct {
+	msg string
+}
+
+func NewInvalidWebhookErr(reason string) InvalidWebhookErr {
+	return InvalidWebhookErr{msg: fmt.Sprintf("invalid webhook: %s", reason)}
+}
+
+func (e InvalidWebhookErr) Error() string {
+	return e.msg
+}
+
+// UnreadableEventErr is returned with a verified event whose object could not be read, the event
+// then only has its id, type, creation time and payload
+type UnreadableEventErr struct {
+	msg string
+}
+
+func NewUnreadableEventErr(eventId, reason string) UnreadableEventErr {
+	return UnreadableEventErr{msg: fmt.Sprintf("event '%s' could not be read: %s", eventId, reason)}
+}
+
+func (e UnreadableEventErr) Error() string {
+	return e.msg
+}
+
+type EventAlreadyExistsErr struct {
+	msg string
+}
+
+func NewEventAlreadyExistsErr(eventId string) EventAlreadyExistsErr {
+	return EventAlreadyExistsErr{msg: fmt.Sprintf("event '%s' already exists", eventId)}
+}
+
+func (e EventAlreadyExistsErr) Error() string {
+	return e.msg
+}
+
+type StaleSubscriptionUpdateErr struct {
+	msg string
+}
+
+func NewStaleSubscriptionUpdateErr(subscriptionId string) StaleSubscriptionUpdateErr {
+	return StaleSubscriptionUpdateErr{msg: fmt.Sprintf("subscription '%s' was already synced from a newer event", subscriptionId)}
+}
+
+func (e StaleSubscriptionUpdateErr) Error() string {
+	return e.msg
+}
+
+type EventNotFoundErr struct {
+	msg string
+}
+
+func NewEventNotFoundErr(eventId string) EventNotFoundErr {
+	return EventNotFoundErr{msg: fmt.Sprintf("event '%s' not found", eventId)}
+}
+
+func (e EventNotFoundErr) Error() string {
+	return e.msg
+}
+
+type ValidationErr struct {
+	msg string
+}
+
+func NewValidationErr(msg string) ValidationErr {
+	return ValidationErr{msg: msg}
+}
+
+func (e ValidationErr) Error() string {
+	return e.msg
+}
+
+type IllegalStatusTransitionErr struct {
+	msg string
+}
+
+func NewIllegalStatusTransitionErr(subscriptionId string, from, to SubscriptionStatus) IllegalStatusTransitionErr {
+	return IllegalStatusTransitionErr{msg: fmt.Sprintf("subscription '%s' can't change from '%s' to '%s'", subscriptionId, from, to)}
+}
+
+func (e IllegalStatusTransitionErr) Error() string {
+	return e.msg
+}
+
+type SubscriptionEndedErr struct {
+	msg string
+}
+
+func NewSubscriptionEndedErr(subscriptionId string, status SubscriptionStatus) SubscriptionEndedErr {
+	return SubscriptionEndedErr{msg: fmt.Sprintf("subscription '%s' has ended with status '%s'", subscriptionId, status)}
+}
+
+func (e SubscriptionEndedErr) Error() string {
+	return e.msg
+}
+
+type AddOnNotFoundErr struct {
+	msg string
+}
+
+func NewAddOnNotFoundErr(subscriptionId, addOn string) AddOnNotFoundErr {
+	return AddOnNotFoundErr{msg: fmt.Sprintf("subscription '%s' has no add-on '%s'", subscriptionId, addOn)}
+}
+
+func (e AddOnNotFoundErr) Error() string {
+	return e.msg
+}
+
+type PaymentMethodNotFoundErr struct {
+	msg string
+}
+
+func NewPaymentMethodNotFoundErr(customerId, paymentMethodId string) PaymentMethodNotFoundErr {
+	return PaymentMethodNotFoundErr{msg: fmt.Sprintf("customer '%s' has no payment method '%s'", customerId, paymentMethodId)}
+}
+
+func (e PaymentMethodNotFoundErr) Error() string {
+	return e.msg
+}
+
+type PlanNotFoundErr struct {
+	msg string
+}
+
+func NewPlanNotFoundErr(planId string) PlanNotFoundErr {
+	return PlanNotFoundErr{msg: fmt.Sprintf("plan '%s' not found", planId)}
+}
+
+func (e PlanNotFoundErr) Error() string {
+	return e.msg
+}
+
+type PlanAlreadyExistsErr struct {
+	msg string
+}
+
+func NewPlanAlreadyExistsErr(planId string) PlanAlreadyExistsErr {
+	return PlanAlreadyExistsErr{msg: fmt.Sprintf("plan '%s' already exists", planId)}
+}
+
+func (e PlanAlreadyExistsErr) Error() string {
+	return e.msg
+}
+
+type StalePlanUpdateErr struct {
+	msg string
+}
+
+func NewStalePlanUpdateErr(planId string) StalePlanUpdateErr {
+	return StalePlanUpdateErr{msg: fmt.Sprintf("plan '%s' was changed since it was read", planId)}
+}
+
+func (e StalePlanUpdateErr) Error() string {
+	return e.msg
+}
+
+type SubscriptionAlreadyExistsErr struct {
+	msg string
+}
+
+func NewSubscriptionAlreadyExistsErr(externalSubscriptionId string) SubscriptionAlreadyExistsErr {
+	return SubscriptionAlreadyExistsErr{msg: fmt.Sprintf("subscription '%s' is already recorded", externalSubscriptionId)}
+}
+
+func (e SubscriptionAlreadyExistsErr) Error() string {
+	return e.msg
+}
+
+type CurrencyMismatchErr struct {
+	msg string
+}
+
+func NewCurrencyMismatchErr(customerId, currency string) CurrencyMismatchErr {
+	return CurrencyMismatchErr{msg: fmt.Sprintf("customer '%s' is billed in another currency than '%s'", customerId, currency)}
+}
+
+func NewSubscriptionCurrencyMismatchErr(subscriptionId, currency string) CurrencyMismatchErr {
+	return CurrencyMismatchErr{msg: fmt.Sprintf("subscription '%s' is billed in another currency than '%s'", subscriptionId, currency)}
+}
+
+func (e CurrencyMismatchErr) Error() string {
+	return e.msg
+}
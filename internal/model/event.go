@@ -0,0 +1,88 @@
+package model
+
+import "time"
+
+const (
+	EventSubscriptionCreated  = "customer.subscription.created"
+	EventSubscriptionUpdated  = "customer.subscription.updated"
+	EventSubscriptionDeleted  = "customer.subscription.deleted"
+	EventSubscriptionPaused   = "customer.subscription.paused"
+	EventSubscriptionResumed  = "customer.subscription.resumed"
+	EventInvoicePaid          = "invoice.paid"
+	EventInvoicePaymentFailed = "invoice.payment_failed"
+	EventCustomerDeleted      = "customer.deleted"
+	// EventCheckoutSessionCompleted is sent once the customer paid on the page of a checkout session
+	EventCheckoutSessionCompleted = "checkout.session.completed"
+)
+
+// Event is a verified payment provider event. Depending on the type
+// only the matching object (Subscription, Invoice, Customer or CheckoutSession) is set.
+// Status, Error and Attempts describe the processing outcome,
+// NextAttemptAt is set while a failed event waits for its next retry.
+type Event struct {
+	EventId         string
+	Type            string
+	CreatedAt       time.Time
+	Payload         []byte
+	Subscription    *EventSubscription
+	Invoice         *EventInvoice
+	Customer        *EventCustomer
+	CheckoutSession *EventCheckoutSession
+	Status          EventStatus
+	Error           string
+	Attempts        int
+	NextAttemptAt   *time.Time
+}
+
+type EventSubscription struct {
+	ExternalSubscriptionId string
+	ExternalCustomerId     string
+	Status                 SubscriptionStatus
+	CancelAtPeriodEnd      bool
+}
+
+type EventInvoice struct {
+	ExternalInvoiceId      string
+	ExternalSubscriptionId string
+	ExternalCustomerId     string
+}
+
+type EventCustomer struct {
+	ExternalCustomerId string
+}
+
+// EventCheckoutSession is a completed checkout. Plan is empty for sessions which were not
+// created by this service, ExternalSubscriptionId for sessions which did not subscribe.
+type EventCheckoutSession struct {
+	ExternalSessionId      string
+	ExternalCustomerId     string
+	ExternalSubscriptionId string
+	Plan                   string
+	Interval               BillingInterval
+	Currency               string
+}
+
+type EventStatus string
+
+const (
+	EventStatusProcessing EventStatus = "processing"
+	EventStatusProcessed  EventStatus = "processed"
+	EventStatusFailed     EventStatus = "failed"
+	EventStatusIgnored    EventStatus = "ignored"
+	// EventStatusManualReview is set once a failed event has used up all of its retries
+	EventStatusManualReview EventStatus = "manual_review"
+)
+
+// IsDone reports whether an event with this status must not be handled again
+func (s EventStatus) IsDone() bool {
+	return s == EventStatusProcessed || s == EventStatusIgnored
+}
+
+// EventFilter selects stored events created in [From, To]. Empty Type and Status match any value.
+type EventFilter struct {
+	Type   string
+	Status EventStatus
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// InvoicePreview is the next invoice of a subscription as it would look after a change. Amounts are
+// in the smallest unit of Currency, ProratedAmount is the sum of the proration lines.
+type InvoicePreview struct {
+	Currency       string
+	Total          int64
+	ProratedAmount int64
+	Lines          []InvoiceLine
+}
+
+type InvoiceLine struct {
+	Description string
+	Amount      int64
+	Proration   bool
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
@@ -0,0 +1,13 @@
+package model
+
+// PaymentMethod is a payment method saved for a customer. The card details are only set for cards.
+// Default marks the method used for subscriptions and invoices.
+type PaymentMethod struct {
+	ExternalPaymentMethodId string
+	Type                    string
+	Brand                   string
+	Last4                   string
+	ExpMonth                int
+	ExpYear                 int
+	Default                 bool
+}
@@ -0,0 +1,92 @@
+package model
+
+// Plan is a plan of the catalog, Prices holds what a seat of the plan costs per billing interval and currency.
+// TrialDays is the default trial of the plan, none if 0. An archived plan can't be subscribed to
+// anymore, existing subscriptions keep it. Version is the number of times the plan was replaced, a
+// replace of a plan read at an older version is rejected.
+type Plan struct {
+	PlanId    string
+	Name      string
+	Prices    []PlanPrice
+	TrialDays int
+	Features  []string
+	Archived  bool
+	Version   int
+}
+
+// Price returns the price of the plan for the interval in the currency, nil if the plan isn't sold
+// with them. Without a currency the first price of the interval is returned, which is the one plans
+// were sold with before they had more than one currency.
+func (p Plan) Price(interval BillingInterval, currency string) *PlanPrice {
+	for i := range p.Prices {
+		if p.Prices[i].Interval == interval && (currency == "" || p.Prices[i].Currency == currency) {
+			return &p.Prices[i]
+		}
+	}
+	return nil
+}
+
+// PlanPrice is what a seat costs per Interval in the smallest unit of Currency. ExternalPriceIds
+// holds the provider price per environment, such as test and live.
+type PlanPrice struct {
+	Interval         BillingInterval
+	Amount           int64
+	Currency         string
+	ExternalPriceIds map[string]string
+}
+
+type BillingInterval string
+
+const (
+	BillingIntervalMonth   BillingInterval = "month"
+	BillingIntervalQuarter BillingInterval = "quarter"
+	BillingIntervalYear    BillingInterval = "year"
+)
+
+// IsValid reports whether the interval is one of the known ones
+func (i BillingInterval) IsValid() bool {
+	return i.Months() > 0
+}
+
+// Months returns the length of the interval in months, 0 for an unknown interval
+func (i BillingInterval) Months() int {
+	switch i {
+	case BillingIntervalMonth:
+		return 1
+	case BillingIntervalQuarter:
+		return 3
+	case BillingIntervalYear:
+		return 12
+	}
+	return 0
+}
+
+// ProviderCatalog is what the payment provider sells in Environment. ArchivedPrices are the prices
+// which can't be subscribed to anymore.
+type ProviderCatalog struct {
+	Environment    string
+	Plans          []ProviderPlan
+	ArchivedPrices []string
+}
+
+// ProviderPlan is a product of the payment provider sold as the plan PlanId, with its active price
+// per billing interval and currency. Prices is empty when the product has no price the plan can be sold with.
+type ProviderPlan struct {
+	PlanId string
+	Name   string
+	Prices []ProviderPrice
+}
+
+type ProviderPrice struct {
+	ExternalPriceId string
+	Interval        BillingInterval
+	Amount          int64
+	Currency        string
+}
+
+// PlanSync lists the plans a sync with the payment provider created, updated and made unavailable
+type PlanSync struct {
+	Created     []string
+	Updated     []string
+	Unavailable []string
+}
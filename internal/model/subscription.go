@@ -1,9 +1,272 @@
 package model
 
+import "time"
+
 type Subscription struct {
 	SubscriptionId         string
 	CustomerId             string
 	ExternalSubscriptionID string
 	Plan                   string
-	Status                 string
+	Interval               BillingInterval
+	// Currency is empty for subscriptions stored before it was recorded
+	Currency string
+	Status   SubscriptionStatus
+	// Quantity is the number of seats paid for
+	Quantity int
+	AddOns   []AddOn
+	// CancelAtPeriodEnd is set when the subscription ends with its current billing period
+	CancelAtPeriodEnd bool
+	// TrialEnd is set for subscriptions created with a free trial
+	TrialEnd *time.Time
+	// LastEventAt is the creation time of the provider event the subscription was last synced from
+	LastEventAt *time.Time
+	// SyncedAt is the local time the status was last read from the provider outside of an event. It is
+	// never compared with LastEventAt, the clocks differ.
+	SyncedAt *time.Time
+	// Payment is only set right after subscribing and is never stored
+	Payment *PaymentConfirmation
+}
+
+// AddOn is an extra item of a subscription besides its plan, such as extra storage
+type AddOn struct {
+	Name           string
+	ExternalItemId string
+	Quantity       int
+}
+
+// ProviderSubscription is a subscription as the payment provider created it
+type ProviderSubscription struct {
+	ExternalSubscriptionId string
+	Status                 SubscriptionStatus
+	TrialEnd               *time.Time
+	Currency               string
+	Payment                *PaymentConfirmation
+}
+
+// PaymentConfirmation is what a client needs to confirm the first payment of a new subscription,
+// or to collect a payment method during a trial. NextAction is the type of action the customer
+// still has to take, such as an authentication, RedirectUrl is set when it is a redirect.
+type PaymentConfirmation struct {
+	Intent       PaymentIntentType
+	ClientSecret string
+	Status       string
+	NextAction   string
+	RedirectUrl  string
+}
+
+type PaymentIntentType string
+
+const (
+	PaymentIntentTypePayment PaymentIntentType = "payment"
+	PaymentIntentTypeSetup   PaymentIntentType = "setup"
+)
+
+// Trial asks for a free trial when subscribing. With PlanDefault the configured trial of the plan is
+// used instead of Days. EndBehavior tells what happens if the trial ends without a payment method,
+// by default the provider invoices anyway.
+type Trial struct {
+	Days        int
+	PlanDefault bool
+	EndBehavior TrialEndBehavior
+}
+
+type TrialEndBehavior string
+
+const (
+	TrialEndBehaviorCancel TrialEndBehavior = "cancel"
+	TrialEndBehaviorPause  TrialEndBehavior = "pause"
+)
+
+// IsValid reports whether the behavior is empty or one of the known ones
+func (b TrialEndBehavior) IsValid() bool {
+	switch b {
+	case "", TrialEndBehaviorCancel, TrialEndBehaviorPause:
+		return true
+	}
+	return false
+}
+
+// Cancellation describes how a subscription is canceled, Reason is an optional comment
+type Cancellation struct {
+	Mode   CancelMode
+	Reason string
+}
+
+type CancelMode string
+
+const (
+	CancelModeImmediately CancelMode = "immediately"
+	// CancelModeAtPeriodEnd keeps the subscription until the end of the period which is already paid
+	CancelModeAtPeriodEnd CancelMode = "at_period_end"
+)
+
+// Pause stops collecting payments of a subscription until it is resumed, or until ResumesAt if set.
+// Behavior tells what happens to the invoices created while paused.
+type Pause struct {
+	ResumesAt *time.Time
+	Behavior  PauseBehavior
+}
+
+type PauseBehavior string
+
+const (
+	PauseBehaviorKeepAsDraft       PauseBehavior = "keep_as_draft"
+	PauseBehaviorMarkUncollectible PauseBehavior = "mark_uncollectible"
+	PauseBehaviorVoid              PauseBehavior = "void"
+)
+
+// IsValid reports whether the behavior is one of the known ones
+func (b PauseBehavior) IsValid() bool {
+	switch b {
+	case PauseBehaviorKeepAsDraft, PauseBehaviorMarkUncollectible, PauseBehaviorVoid:
+		return true
+	}
+	return false
+}
+
+// QuantityChange sets the number of seats of a subscription
+type QuantityChange struct {
+	Quantity          int
+	ProrationBehavior ProrationBehavior
+}
+
+// PlanChange moves a subscription to another plan or billing interval, an empty Interval keeps the
+// current one. ProrationBehavior controls how the unused time of the current plan is billed. Currency
+// is the one of the customer, the new price is in it.
+type PlanChange struct {
+	Plan              string
+	Interval          BillingInterval
+	Currency          string
+	ProrationBehavior ProrationBehavior
+}
+
+type ProrationBehavior string
+
+const (
+	// ProrationBehaviorCreateProrations adds the prorated amount to the next invoice
+	ProrationBehaviorCreateProrations ProrationBehavior = "create_prorations"
+	ProrationBehaviorNone             ProrationBehavior = "none"
+	// ProrationBehaviorAlwaysInvoice invoices the prorated amount right away
+	ProrationBehaviorAlwaysInvoice ProrationBehavior = "always_invoice"
+)
+
+// IsValid reports whether the behavior is one of the known ones
+func (b ProrationBehavior) IsValid() bool {
+	switch b {
+	case ProrationBehaviorCreateProrations, ProrationBehaviorNone, ProrationBehaviorAlwaysInvoice:
+		return true
+	}
+	return false
+}
+
+// StatusChange is an entry of the status history of a subscription. EventId is set for changes
+// made by a provider event.
+type StatusChange struct {
+	PreviousStatus SubscriptionStatus
+	Status         SubscriptionStatus
+	Source         StatusChangeSource
+	EventId        string
+	ChangedAt      time.Time
+}
+
+// StatusChangeSource tells what changed the status of a subscription
+type StatusChangeSource string
+
+const (
+	StatusChangeSourceApi     StatusChangeSource = "api"
+	StatusChangeSourceWebhook StatusChangeSource = "webhook"
+	// StatusChangeSourceReconciliation is a status refreshed from the provider on read
+	StatusChangeSourceReconciliation StatusChangeSource = "reconciliation"
+)
+
+// SubscriptionStatus is the lifecycle state of a subscription, provider statuses are mapped onto it
+type SubscriptionStatus string
+
+const (
+	// SubscriptionStatusIncomplete is the initial status until the first payment succeeds
+	SubscriptionStatusIncomplete SubscriptionStatus = "incomplete"
+	// SubscriptionStatusIncompleteExpired is final, the first payment did not succeed in time
+	SubscriptionStatusIncompleteExpired SubscriptionStatus = "incomplete_expired"
+	SubscriptionStatusTrialing          SubscriptionStatus = "trialing"
+	SubscriptionStatusActive            SubscriptionStatus = "active"
+	// SubscriptionStatusPastDue is set when a renewal payment failed and is being retried
+	SubscriptionStatusPastDue SubscriptionStatus = "past_due"
+	// SubscriptionStatusUnpaid is set when all retries of a renewal payment failed
+	SubscriptionStatusUnpaid SubscriptionStatus = "unpaid"
+	SubscriptionStatusPaused SubscriptionStatus = "paused"
+	// SubscriptionStatusCanceled is final
+	SubscriptionStatusCanceled SubscriptionStatus = "canceled"
+)
+
+// subscriptionTransitions lists the statuses each status may change to
+var subscriptionTransitions = map[SubscriptionStatus][]SubscriptionStatus{
+	SubscriptionStatusIncomplete: {
+		SubscriptionStatusIncompleteExpired,
+		SubscriptionStatusTrialing,
+		SubscriptionStatusActive,
+		SubscriptionStatusCanceled,
+	},
+	SubscriptionStatusIncompleteExpired: {},
+	SubscriptionStatusTrialing: {
+		SubscriptionStatusActive,
+		SubscriptionStatusPastDue,
+		SubscriptionStatusUnpaid,
+		SubscriptionStatusPaused,
+		SubscriptionStatusCanceled,
+	},
+	SubscriptionStatusActive: {
+		SubscriptionStatusTrialing,
+		SubscriptionStatusPastDue,
+		SubscriptionStatusUnpaid,
+		SubscriptionStatusPaused,
+		SubscriptionStatusCanceled,
+	},
+	SubscriptionStatusPastDue: {
+		SubscriptionStatusActive,
+		SubscriptionStatusUnpaid,
+		SubscriptionStatusPaused,
+		SubscriptionStatusCanceled,
+	},
+	SubscriptionStatusUnpaid: {
+		SubscriptionStatusActive,
+		SubscriptionStatusCanceled,
+	},
+	// A subscription paused at the end of its trial resumes trialing, and one paused with open invoices
+	// resumes in dunning
+	SubscriptionStatusPaused: {
+		SubscriptionStatusTrialing,
+		SubscriptionStatusActive,
+		SubscriptionStatusPastDue,
+		SubscriptionStatusUnpaid,
+		SubscriptionStatusCanceled,
+	},
+	SubscriptionStatusCanceled: {},
+}
+
+// IsValid reports whether the status is one of the defined statuses
+func (s SubscriptionStatus) IsValid() bool {
+	_, ok := subscriptionTransitions[s]
+	return ok
+}
+
+// IsEnded reports whether the subscription reached a final status and can't be changed anymore
+func (s SubscriptionStatus) IsEnded() bool {
+	return s == SubscriptionStatusCanceled || s == SubscriptionStatusIncompleteExpired
+}
+
+// CanTransitionTo reports whether a subscription may change from this status to next. Keeping the
+// status is always allowed. Statuses stored before the status set was defined may change to any status.
+func (s SubscriptionStatus) CanTransitionTo(next SubscriptionStatus) bool {
+	if !next.IsValid() {
+		return false
+	}
+	if s == next || !s.IsValid() {
+		return true
+	}
+	for _, allowed := range subscriptionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
 }
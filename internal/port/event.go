@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"time"
+)
+
+type Event interface {
+	CreateEvent(ctx context.Context, event model.Event) error
+	GetEvent(ctx context.Context, eventId string) (*model.Event, error)
+	UpdateEvent(ctx context.Context, event model.Event) error
+	GetEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error)
+	GetDueEvents(ctx context.Context, before time.Time, limit int) ([]model.Event, error)
+	ClaimEvent(ctx context.Context, event model.Event, until time.Time) (bool, error)
+}
@@ -7,6 +7,24 @@ import (
 
 type PaymentProvider interface {
 	CreateCustomer(ctx context.Context, email string) (string, error)
-	SubscribeCustomer(ctx context.Context, customer model.Customer, plan string) (string, error)
-	GetSubscriptionStatus(ctx context.Context, subscriptionId string) (string, error)
+	SubscribeCustomer(ctx context.Context, customer model.Customer, plan string, interval model.BillingInterval, currency string, quantity int, trial *model.Trial) (model.ProviderSubscription, error)
+	GetSubscriptionStatus(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error)
+	GetSubscriptionCurrency(ctx context.Context, subscriptionId string) (string, error)
+	CancelSubscription(ctx context.Context, subscriptionId string, cancellation model.Cancellation) (model.SubscriptionStatus, error)
+	PauseSubscription(ctx context.Context, subscriptionId string, pause model.Pause) (model.SubscriptionStatus, error)
+	ResumeSubscription(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error)
+	ChangePlan(ctx context.Context, subscriptionId string, change model.PlanChange) error
+	ChangeQuantity(ctx context.Context, subscriptionId string, change model.QuantityChange) error
+	AddAddOn(ctx context.Context, subscriptionId, addOn, currency string, quantity int) (string, error)
+	RemoveAddOn(ctx context.Context, externalItemId string) error
+	CreateCheckoutSession(ctx context.Context, customer model.Customer, checkout model.Checkout) (model.CheckoutSession, error)
+	CreatePortalSession(ctx context.Context, customer model.Customer) (string, error)
+	CreateSetupIntent(ctx context.Context, customer model.Customer) (model.PaymentConfirmation, error)
+	PaymentMethods(ctx context.Context, customer model.Customer) ([]model.PaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, customer model.Customer, paymentMethodId string) error
+	DetachPaymentMethod(ctx context.Context, paymentMethodId string) error
+	GetPlanCatalog(ctx context.Context) (model.ProviderCatalog, error)
+	PreviewPlanChange(ctx context.Context, subscriptionId string, change model.PlanChange) (model.InvoicePreview, error)
+	ConstructEvent(ctx context.Context, payload []byte, signature string) (model.Event, error)
+	ParseEvent(ctx context.Context, payload []byte) (model.Event, error)
 }
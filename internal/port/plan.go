@@ -0,0 +1,13 @@
+package port
+
+import (
+	"context"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+)
+
+type Plan interface {
+	GetPlan(ctx context.Context, planId string) (*model.Plan, error)
+	GetPlans(ctx context.Context) ([]model.Plan, error)
+	CreatePlan(ctx context.Context, plan model.Plan) error
+	PutPlan(ctx context.Context, plan model.Plan) error
+}
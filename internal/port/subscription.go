@@ -8,6 +8,15 @@ import (
 type Subscription interface {
 	CreateCustomer(ctx context.Context, customer model.Customer) error
 	GetCustomer(ctx context.Context, id string) (*model.Customer, error)
+	SetCustomerCurrency(ctx context.Context, customerId, currency string) error
 	CreateSubscription(ctx context.Context, subscription model.Subscription) error
 	GetSubscription(ctx context.Context, customerId, subscriptionId string) (*model.Subscription, error)
+	GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*model.Customer, error)
+	GetSubscriptions(ctx context.Context, customerId string) ([]model.Subscription, error)
+	GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*model.Subscription, error)
+	UpdateSubscriptionStatus(ctx context.Context, subscription model.Subscription, change *model.StatusChange) error
+	UpdateSubscriptionPlan(ctx context.Context, subscription model.Subscription) error
+	UpdateSubscriptionQuantity(ctx context.Context, subscription model.Subscription) error
+	UpdateSubscriptionAddOns(ctx context.Context, subscription model.Subscription) error
+	GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]model.StatusChange, error)
 }
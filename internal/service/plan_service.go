@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/port"
+	"github.com/pkg/errors"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+type PlanService interface {
+	Plans(ctx context.Context) ([]model.Plan, error)
+	Plan(ctx context.Context, planId string) (model.Plan, error)
+	CreatePlan(ctx context.Context, plan model.Plan) (model.Plan, error)
+	UpdatePlan(ctx context.Context, plan model.Plan) (model.Plan, error)
+	ArchivePlan(ctx context.Context, planId string) (model.Plan, error)
+	SyncPlans(ctx context.Context) (model.PlanSync, error)
+}
+
+// currencyPattern matches lowercase ISO 4217 currency codes, as the payment provider uses them
+var currencyPattern = regexp.MustCompile(`^[a-z]{3}$`)
+
+type planService struct {
+	plans           port.Plan
+	paymentProvider port.PaymentProvider
+}
+
+func NewPlanService(plans port.Plan, paymentProvider port.PaymentProvider) PlanService {
+	return &planService{
+		plans:           plans,
+		paymentProvider: paymentProvider,
+	}
+}
+
+// Plans returns the plans which can be subscribed to, archived plans are left out
+func (s planService) Plans(ctx context.Context) ([]model.Plan, error) {
+	plans, err := s.plans.GetPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.Plan, 0, len(plans))
+	for _, plan := range plans {
+		if !plan.Archived {
+			res = append(res, plan)
+		}
+	}
+	// Cheapest first, the way a pricing page lists them
+	sort.SliceStable(res, func(i, j int) bool {
+		return monthlyAmount(res[i]) < monthlyAmount(res[j])
+	})
+
+	return res, nil
+}
+
+// Plan returns archived plans as well, subscriptions may still be on them
+func (s planService) Plan(ctx context.Context, planId string) (model.Plan, error) {
+	plan, err := s.findPlan(ctx, planId)
+	if err != nil {
+		return model.Plan{}, err
+	}
+
+	return *plan, nil
+}
+
+func (s planService) CreatePlan(ctx context.Context, plan model.Plan) (model.Plan, error) {
+	if plan.PlanId == "" {
+		return model.Plan{}, model.NewValidationErr("plan id is required")
+	}
+	if err := validatePlan(plan); err != nil {
+		return model.Plan{}, err
+	}
+
+	plan.Archived = false
+	err := s.plans.CreatePlan(ctx, plan)
+	if err != nil {
+		return model.Plan{}, err
+	}
+
+	return plan, nil
+}
+
+// UpdatePlan replaces everything but the archived flag of the plan. It fails with a model.StalePlanUpdateErr
+// if the plan is changed meanwhile, by the sync with the payment provider for instance.
+func (s planService) UpdatePlan(ctx context.Context, plan model.Plan) (model.Plan, error) {
+	if err := validatePlan(plan); err != nil {
+		return model.Plan{}, err
+	}
+	stored, err := s.findPlan(ctx, plan.PlanId)
+	if err != nil {
+		return model.Plan{}, err
+	}
+
+	plan.Archived = stored.Archived
+	plan.Version = stored.Version
+	err = s.plans.PutPlan(ctx, plan)
+	if err != nil {
+		return model.Plan{}, err
+	}
+
+	return plan, nil
+}
+
+// ArchivePlan takes the plan off sale, existing subscriptions are not changed
+func (s planService) ArchivePlan(ctx context.Context, planId string) (model.Plan, error) {
+	plan, err := s.findPlan(ctx, planId)
+	if err != nil {
+		return model.Plan{}, err
+	}
+	if plan.Archived {
+		return *plan, nil
+	}
+
+	plan.Archived = true
+	err = s.plans.PutPlan(ctx, *plan)
+	if err != nil {
+		return model.Plan{}, err
+	}
+
+	return *plan, nil
+}
+
+// SyncPlans upserts the plans the payment provider sells into the catalog. A billing interval and currency
+// whose price is archived loses it, which makes the plan unavailable with them in the environment.
+// Trial days, features, the archived flag and plans the provider doesn't sell are left as they are.
+func (s planService) SyncPlans(ctx context.Context) (model.PlanSync, error) {
+	catalog, err := s.paymentProvider.GetPlanCatalog(ctx)
+	if err != nil {
+		return model.PlanSync{}, err
+	}
+	plans, err := s.plans.GetPlans(ctx)
+	if err != nil {
+		return model.PlanSync{}, err
+	}
+
+	stored := make(map[string]model.Plan, len(plans))
+	for _, plan := range plans {
+		stored[plan.PlanId] = plan
+	}
+	archivedPrices := make(map[string]bool, len(catalog.ArchivedPrices))
+	for _, price := range catalog.ArchivedPrices {
+		archivedPrices[price] = true
+	}
+
+	var res model.PlanSync
+	synced := make(map[string]bool, len(catalog.Plans))
+	for _, providerPlan := range catalog.Plans {
+		synced[providerPlan.PlanId] = true
+		plan, ok := stored[providerPlan.PlanId]
+		if !ok {
+			// Nothing to sell a new plan with yet
+			if len(providerPlan.Prices) == 0 {
+				continue
+			}
+			plan = model.Plan{
+				PlanId: providerPlan.PlanId,
+				Name:   providerPlan.Name,
+				Prices: syncPlanPrices(nil, providerPlan.Prices, catalog.Environment, nil),
+			}
+			err := s.plans.CreatePlan(ctx, plan)
+			var existsErr model.PlanAlreadyExistsErr
+			if errors.As(err, &existsErr) {
+				// The sync of another instance created it
+				continue
+			}
+			if err != nil {
+				return res, err
+			}
+			res.Created = append(res.Created, plan.PlanId)
+			continue
+		}
+
+		err := s.putSyncedPlan(ctx, plan, catalog.Environment, &res, func(updated model.Plan) model.Plan {
+			updated.Name = providerPlan.Name
+			// The provider sells the plan with its active prices only
+			updated.Prices = syncPlanPrices(updated.Prices, providerPlan.Prices, catalog.Environment, func(model.PlanPrice) bool {
+				return false
+			})
+			return updated
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+
+	// Plans of products which aren't sold anymore, or lost their plan metadata
+	for _, plan := range plans {
+		if synced[plan.PlanId] {
+			continue
+		}
+		err := s.putSyncedPlan(ctx, plan, catalog.Environment, &res, func(updated model.Plan) model.Plan {
+			updated.Prices = syncPlanPrices(updated.Prices, nil, catalog.Environment, func(price model.PlanPrice) bool {
+				return !archivedPrices[price.ExternalPriceIds[catalog.Environment]]
+			})
+			return updated
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// putSyncedPlan stores the plan if sync changed it and records how. The sync runs on every instance and
+// plans are changed by admins too, a plan changed since it was read is read again and synced once more.
+func (s planService) putSyncedPlan(ctx context.Context, plan model.Plan, environment string, res *model.PlanSync, sync func(model.Plan) model.Plan) error {
+	updated := sync(plan)
+	if reflect.DeepEqual(plan, updated) {
+		return nil
+	}
+	err := s.plans.PutPlan(ctx, updated)
+	var staleErr model.StalePlanUpdateErr
+	if errors.As(err, &staleErr) {
+		stored, findErr := s.findPlan(ctx, plan.PlanId)
+		if findErr != nil {
+			return findErr
+		}
+		plan = *stored
+		updated = sync(plan)
+		if reflect.DeepEqual(plan, updated) {
+			return nil
+		}
+		err = s.plans.PutPlan(ctx, updated)
+	}
+	if err != nil {
+		return err
+	}
+	if isSoldIn(plan, environment) && !isSoldIn(updated, environment) {
+		res.Unavailable = append(res.Unavailable, updated.PlanId)
+	} else {
+		res.Updated = append(res.Updated, updated.PlanId)
+	}
+	return nil
+}
+
+// priceKey identifies a price of a plan, a plan has one price per billing interval and currency
+type priceKey struct {
+	interval model.BillingInterval
+	currency string
+}
+
+// syncPlanPrices sets the provider prices of the environment on the prices of a plan. A price the
+// provider has no price for loses its price in the environment unless it is still sold, and is
+// dropped once it has no price in any environment. New currencies come after the ones the plan has,
+// so the main currency of the plan stays the same.
+func syncPlanPrices(prices []model.PlanPrice, providerPrices []model.ProviderPrice, environment string, stillSold func(model.PlanPrice) bool) []model.PlanPrice {
+	byKey := make(map[priceKey]model.ProviderPrice, len(providerPrices))
+	for _, providerPrice := range providerPrices {
+		byKey[priceKey{providerPrice.Interval, providerPrice.Currency}] = providerPrice
+	}
+
+	var res []model.PlanPrice
+	for _, price := range prices {
+		price.ExternalPriceIds = maps.Clone(price.ExternalPriceIds)
+		key := priceKey{price.Interval, price.Currency}
+		if providerPrice, ok := byKey[key]; ok {
+			delete(byKey, key)
+			if price.ExternalPriceIds == nil {
+				price.ExternalPriceIds = make(map[string]string)
+			}
+			price.ExternalPriceIds[environment] = providerPrice.ExternalPriceId
+			price.Amount = providerPrice.Amount
+		} else if price.ExternalPriceIds[environment] != "" && !stillSold(price) {
+			delete(price.ExternalPriceIds, environment)
+			if len(price.ExternalPriceIds) == 0 {
+				continue
+			}
+		}
+		res = append(res, price)
+	}
+	for _, providerPrice := range providerPrices {
+		if _, ok := byKey[priceKey{providerPrice.Interval, providerPrice.Currency}]; ok {
+			res = append(res, model.PlanPrice{
+				Interval:         providerPrice.Interval,
+				Amount:           providerPrice.Amount,
+				Currency:         providerPrice.Currency,
+				ExternalPriceIds: map[string]string{environment: providerPrice.ExternalPriceId},
+			})
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Interval.Months() < res[j].Interval.Months()
+	})
+	return res
+}
+
+// isSoldIn reports whether the plan has a price in the environment
+func isSoldIn(plan model.Plan, environment string) bool {
+	for _, price := range plan.Prices {
+		if price.ExternalPriceIds[environment] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// monthlyAmount returns the lowest amount of the plan per month in its main currency, the one of its
+// first price. Prices of an unknown interval are left out, plans without prices come last.
+func monthlyAmount(plan model.Plan) int64 {
+	res := int64(math.MaxInt64)
+	for _, price := range plan.Prices {
+		if price.Currency == plan.Prices[0].Currency && price.Interval.Months() > 0 {
+			res = min(res, price.Amount/int64(price.Interval.Months()))
+		}
+	}
+	return res
+}
+
+func (s planService) findPlan(ctx context.Context, planId string) (*model.Plan, error) {
+	plan, err := s.plans.GetPlan(ctx, planId)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, model.NewPlanNotFoundErr(planId)
+	}
+	return plan, nil
+}
+
+func validatePlan(plan model.Plan) error {
+	if plan.Name == "" {
+		return model.NewValidationErr("plan name is required")
+	}
+	keys := make(map[priceKey]bool, len(plan.Prices))
+	for _, price := range plan.Prices {
+		if !price.Interval.IsValid() {
+			return model.NewValidationErr(fmt.Sprintf("unknown billing interval '%s'", price.Interval))
+		}
+		if !currencyPattern.MatchString(price.Currency) {
+			return model.NewValidationErr(fmt.Sprintf("currency '%s' is not a lowercase ISO 4217 code", price.Currency))
+		}
+		key := priceKey{price.Interval, price.Currency}
+		if keys[key] {
+			return model.NewValidationErr(fmt.Sprintf("more than one %s price in %s", price.Interval, price.Currency))
+		}
+		keys[key] = true
+		if price.Amount < 0 {
+			return model.NewValidationErr("amount must not be negative")
+		}
+	}
+	if plan.TrialDays < 0 || plan.TrialDays > maxTrialDays {
+		return model.NewValidationErr(fmt.Sprintf("trial days must be between 0 and %d", maxTrialDays))
+	}
+	return nil
+}
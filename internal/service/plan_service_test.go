@@ -0,0 +1,402 @@
+//go:build unit
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+)
+
+func newCorePlan() model.Plan {
+	return model.Plan{
+		PlanId: "Core",
+		Name:   "Core",
+		Prices: []model.PlanPrice{
+			{Interval: model.BillingIntervalMonth, Amount: 900, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_core"}},
+		},
+		TrialDays: 14,
+	}
+}
+
+// concurrentPlanCatalog changes the catalog right before the first put, the way an admin or the sync
+// of another instance would
+type concurrentPlanCatalog struct {
+	planCatalog
+	change func(catalog planCatalog)
+}
+
+func (c *concurrentPlanCatalog) PutPlan(ctx context.Context, plan model.Plan) error {
+	if c.change != nil {
+		c.change(c.planCatalog)
+		c.change = nil
+	}
+	return c.planCatalog.PutPlan(ctx, plan)
+}
+
+// priced returns a plan with a single price
+func priced(planId string, interval model.BillingInterval, amount int64) model.Plan {
+	return model.Plan{PlanId: planId, Prices: []model.PlanPrice{{Interval: interval, Amount: amount, Currency: "usd"}}}
+}
+
+func TestPlans_SkipsArchivedAndSortsByAmount(t *testing.T) {
+	ctx := context.Background()
+
+	legacy := priced("Legacy", model.BillingIntervalMonth, 500)
+	legacy.Archived = true
+	// Amounts in other currencies than the main one aren't compared
+	growth := priced("Growth", model.BillingIntervalMonth, 2900)
+	growth.Prices = append(growth.Prices, model.PlanPrice{Interval: model.BillingIntervalMonth, Amount: 100, Currency: "huf"})
+	catalog := planCatalog{
+		// Compared per month
+		"Premium": priced("Premium", model.BillingIntervalYear, 48000),
+		"Core":    priced("Core", model.BillingIntervalQuarter, 2700),
+		"Legacy":  legacy,
+		"Growth":  growth,
+		"Draft":   {PlanId: "Draft"},
+	}
+
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	res, err := svc.Plans(ctx)
+	assert.NoError(t, err)
+
+	ids := make([]string, 0, len(res))
+	for _, plan := range res {
+		ids = append(ids, plan.PlanId)
+	}
+	assert.Equal(t, []string{"Core", "Growth", "Premium", "Draft"}, ids)
+}
+
+func TestPlans_UnknownInterval(t *testing.T) {
+	ctx := context.Background()
+
+	// A price of an interval this version doesn't know is not compared
+	core := priced("Core", model.BillingIntervalMonth, 900)
+	core.Prices = append(core.Prices, model.PlanPrice{Interval: "week", Amount: 100, Currency: "usd"})
+	catalog := planCatalog{
+		"Core":   core,
+		"Weekly": priced("Weekly", "week", 100),
+		"Growth": priced("Growth", model.BillingIntervalMonth, 2900),
+	}
+
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	res, err := svc.Plans(ctx)
+	assert.NoError(t, err)
+
+	ids := make([]string, 0, len(res))
+	for _, plan := range res {
+		ids = append(ids, plan.PlanId)
+	}
+	assert.Equal(t, []string{"Core", "Growth", "Weekly"}, ids)
+}
+
+func TestPlan_Archived(t *testing.T) {
+	ctx := context.Background()
+
+	svc := service.NewPlanService(planCatalog{"Legacy": {PlanId: "Legacy", Archived: true}}, new(mockPaymentProvider))
+	res, err := svc.Plan(ctx, "Legacy")
+	assert.NoError(t, err)
+	assert.True(t, res.Archived)
+}
+
+func TestPlan_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	svc := service.NewPlanService(planCatalog{}, new(mockPaymentProvider))
+	_, err := svc.Plan(ctx, "Unknown")
+	assert.IsType(t, model.PlanNotFoundErr{}, err)
+}
+
+func TestCreatePlan_Success(t *testing.T) {
+	ctx := context.Background()
+
+	catalog := planCatalog{}
+	plan := newCorePlan()
+	plan.Archived = true
+
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	res, err := svc.CreatePlan(ctx, plan)
+	assert.NoError(t, err)
+
+	// A new plan is always on sale
+	assert.False(t, res.Archived)
+	assert.Equal(t, res, catalog["Core"])
+}
+
+func TestCreatePlan_Invalid(t *testing.T) {
+	ctx := context.Background()
+
+	catalog := planCatalog{}
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	for name, change := range map[string]func(plan *model.Plan){
+		"no id":          func(plan *model.Plan) { plan.PlanId = "" },
+		"no name":        func(plan *model.Plan) { plan.Name = "" },
+		"interval":       func(plan *model.Plan) { plan.Prices[0].Interval = "week" },
+		"same price":     func(plan *model.Plan) { plan.Prices = append(plan.Prices, plan.Prices[0]) },
+		"currency":       func(plan *model.Plan) { plan.Prices[0].Currency = "USD" },
+		"amount":         func(plan *model.Plan) { plan.Prices[0].Amount = -1 },
+		"trial too long": func(plan *model.Plan) { plan.TrialDays = 1000 },
+	} {
+		plan := newCorePlan()
+		change(&plan)
+		_, err := svc.CreatePlan(ctx, plan)
+		assert.IsType(t, model.ValidationErr{}, err, name)
+	}
+
+	assert.Empty(t, catalog)
+}
+
+func TestCreatePlan_Currencies(t *testing.T) {
+	ctx := context.Background()
+
+	plan := newCorePlan()
+	plan.Prices = append(plan.Prices,
+		model.PlanPrice{Interval: model.BillingIntervalMonth, Amount: 850, Currency: "eur"},
+		model.PlanPrice{Interval: model.BillingIntervalMonth, Amount: 750, Currency: "gbp"},
+	)
+
+	svc := service.NewPlanService(planCatalog{}, new(mockPaymentProvider))
+	res, err := svc.CreatePlan(ctx, plan)
+	assert.NoError(t, err)
+	assert.Len(t, res.Prices, 3)
+}
+
+func TestCreatePlan_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+
+	svc := service.NewPlanService(planCatalog{"Core": newCorePlan()}, new(mockPaymentProvider))
+	_, err := svc.CreatePlan(ctx, newCorePlan())
+	assert.IsType(t, model.PlanAlreadyExistsErr{}, err)
+}
+
+func TestUpdatePlan_KeepsArchived(t *testing.T) {
+	ctx := context.Background()
+
+	stored := newCorePlan()
+	stored.Archived = true
+	catalog := planCatalog{"Core": stored}
+
+	plan := newCorePlan()
+	plan.Prices[0].Amount = 1200
+
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	res, err := svc.UpdatePlan(ctx, plan)
+	assert.NoError(t, err)
+	assert.True(t, res.Archived)
+	assert.Equal(t, int64(1200), catalog["Core"].Prices[0].Amount)
+}
+
+func TestUpdatePlan_ChangedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+
+	catalog := &concurrentPlanCatalog{planCatalog: planCatalog{"Core": newCorePlan()}, change: func(catalog planCatalog) {
+		archived := catalog["Core"]
+		archived.Archived = true
+		archived.Version++
+		catalog["Core"] = archived
+	}}
+
+	plan := newCorePlan()
+	plan.Prices[0].Amount = 1200
+
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	_, err := svc.UpdatePlan(ctx, plan)
+	assert.IsType(t, model.StalePlanUpdateErr{}, err)
+	assert.True(t, catalog.planCatalog["Core"].Archived)
+	assert.Equal(t, int64(900), catalog.planCatalog["Core"].Prices[0].Amount)
+}
+
+func TestUpdatePlan_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	catalog := planCatalog{}
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+	_, err := svc.UpdatePlan(ctx, newCorePlan())
+	assert.IsType(t, model.PlanNotFoundErr{}, err)
+	assert.Empty(t, catalog)
+}
+
+func TestArchivePlan(t *testing.T) {
+	ctx := context.Background()
+
+	catalog := planCatalog{"Core": newCorePlan()}
+	svc := service.NewPlanService(catalog, new(mockPaymentProvider))
+
+	res, err := svc.ArchivePlan(ctx, "Core")
+	assert.NoError(t, err)
+	assert.True(t, res.Archived)
+	assert.True(t, catalog["Core"].Archived)
+
+	// Archiving twice is not an error
+	_, err = svc.ArchivePlan(ctx, "Core")
+	assert.NoError(t, err)
+}
+
+func TestSyncPlans(t *testing.T) {
+	ctx := context.Background()
+
+	mockPay := new(mockPaymentProvider)
+
+	growth := model.Plan{PlanId: "Growth", Name: "Growth", Features: []string{"10 projects"}, Prices: []model.PlanPrice{
+		{Interval: model.BillingIntervalMonth, Amount: 2500, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_growth"}},
+		{Interval: model.BillingIntervalQuarter, Amount: 7000, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_growth_quarterly"}},
+	}}
+	legacy := model.Plan{PlanId: "Legacy", Prices: []model.PlanPrice{
+		{Interval: model.BillingIntervalMonth, Amount: 500, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_legacy", "live": "price_legacy_live"}},
+		{Interval: model.BillingIntervalYear, Amount: 5000, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_legacy_yearly"}},
+	}}
+	manual := priced("Manual", model.BillingIntervalMonth, 100)
+	manual.Prices[0].ExternalPriceIds = map[string]string{"test": "price_manual"}
+	catalog := planCatalog{
+		"Core":   newCorePlan(),
+		"Growth": growth,
+		"Legacy": legacy,
+		"Manual": manual,
+	}
+
+	mockPay.
+		On("GetPlanCatalog", ctx).
+		Return(model.ProviderCatalog{
+			Environment: "test",
+			Plans: []model.ProviderPlan{
+				// Unchanged
+				{PlanId: "Core", Name: "Core", Prices: []model.ProviderPrice{
+					{ExternalPriceId: "price_core", Interval: model.BillingIntervalMonth, Amount: 900, Currency: "usd"},
+				}},
+				// A new monthly price, a yearly one and one in eur, the quarterly one isn't sold anymore
+				{PlanId: "Growth", Name: "Growth", Prices: []model.ProviderPrice{
+					{ExternalPriceId: "price_growth_yearly", Interval: model.BillingIntervalYear, Amount: 29000, Currency: "usd"},
+					{ExternalPriceId: "price_growth_eur", Interval: model.BillingIntervalMonth, Amount: 2700, Currency: "eur"},
+					{ExternalPriceId: "price_growth_new", Interval: model.BillingIntervalMonth, Amount: 2900, Currency: "usd"},
+				}},
+				{PlanId: "Premium", Name: "Premium", Prices: []model.ProviderPrice{
+					{ExternalPriceId: "price_premium", Interval: model.BillingIntervalMonth, Amount: 4900, Currency: "usd"},
+				}},
+				{PlanId: "Enterprise", Name: "Enterprise"},
+			},
+			ArchivedPrices: []string{"price_growth", "price_legacy", "price_legacy_yearly"},
+		}, nil).Once()
+
+	svc := service.NewPlanService(catalog, mockPay)
+	res, err := svc.SyncPlans(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, model.PlanSync{
+		Created:     []string{"Premium"},
+		Updated:     []string{"Growth"},
+		Unavailable: []string{"Legacy"},
+	}, res)
+
+	assert.Equal(t, newCorePlan(), catalog["Core"])
+
+	// Trial days and features stay as they are, usd stays the main currency
+	assert.Equal(t, model.Plan{
+		PlanId:   "Growth",
+		Name:     "Growth",
+		Features: []string{"10 projects"},
+		Version:  1,
+		Prices: []model.PlanPrice{
+			{Interval: model.BillingIntervalMonth, Amount: 2900, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_growth_new"}},
+			{Interval: model.BillingIntervalMonth, Amount: 2700, Currency: "eur", ExternalPriceIds: map[string]string{"test": "price_growth_eur"}},
+			{Interval: model.BillingIntervalYear, Amount: 29000, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_growth_yearly"}},
+		},
+	}, catalog["Growth"])
+	assert.Equal(t, model.Plan{
+		PlanId: "Premium",
+		Name:   "Premium",
+		Prices: []model.PlanPrice{
+			{Interval: model.BillingIntervalMonth, Amount: 4900, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_premium"}},
+		},
+	}, catalog["Premium"])
+	assert.NotContains(t, catalog, "Enterprise")
+
+	// The archived prices are removed, the plan is left in the other environments
+	assert.Equal(t, []model.PlanPrice{
+		{Interval: model.BillingIntervalMonth, Amount: 500, Currency: "usd", ExternalPriceIds: map[string]string{"live": "price_legacy_live"}},
+	}, catalog["Legacy"].Prices)
+	assert.False(t, catalog["Legacy"].Archived)
+	assert.Equal(t, manual, catalog["Manual"])
+
+	mockPay.AssertExpectations(t)
+}
+
+func TestSyncPlans_PlanWithoutActivePrice(t *testing.T) {
+	ctx := context.Background()
+
+	mockPay := new(mockPaymentProvider)
+	catalog := planCatalog{"Core": newCorePlan()}
+
+	mockPay.
+		On("GetPlanCatalog", ctx).
+		Return(model.ProviderCatalog{
+			Environment: "test",
+			Plans:       []model.ProviderPlan{{PlanId: "Core", Name: "Core"}},
+		}, nil).Once()
+
+	svc := service.NewPlanService(catalog, mockPay)
+	res, err := svc.SyncPlans(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Core"}, res.Unavailable)
+	assert.Empty(t, catalog["Core"].Prices)
+
+	mockPay.AssertExpectations(t)
+}
+
+func TestSyncPlans_PlanChangedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+
+	mockPay := new(mockPaymentProvider)
+	// An admin changes the trial of the plan while it is synced
+	catalog := &concurrentPlanCatalog{planCatalog: planCatalog{"Core": newCorePlan()}, change: func(catalog planCatalog) {
+		changed := catalog["Core"]
+		changed.TrialDays = 30
+		changed.Version++
+		catalog["Core"] = changed
+	}}
+
+	mockPay.
+		On("GetPlanCatalog", ctx).
+		Return(model.ProviderCatalog{
+			Environment: "test",
+			Plans: []model.ProviderPlan{
+				{PlanId: "Core", Name: "Core", Prices: []model.ProviderPrice{
+					{ExternalPriceId: "price_core_new", Interval: model.BillingIntervalMonth, Amount: 1000, Currency: "usd"},
+				}},
+			},
+		}, nil).Once()
+
+	svc := service.NewPlanService(catalog, mockPay)
+	res, err := svc.SyncPlans(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Core"}, res.Updated)
+
+	// The sync is applied on top of the change of the admin
+	core := catalog.planCatalog["Core"]
+	assert.Equal(t, 30, core.TrialDays)
+	assert.Equal(t, 2, core.Version)
+	assert.Equal(t, []model.PlanPrice{
+		{Interval: model.BillingIntervalMonth, Amount: 1000, Currency: "usd", ExternalPriceIds: map[string]string{"test": "price_core_new"}},
+	}, core.Prices)
+
+	mockPay.AssertExpectations(t)
+}
+
+func TestSyncPlans_ProviderError(t *testing.T) {
+	ctx := context.Background()
+
+	mockPay := new(mockPaymentProvider)
+	catalog := planCatalog{"Core": newCorePlan()}
+
+	mockPay.
+		On("GetPlanCatalog", ctx).
+		Return(model.ProviderCatalog{}, errors.New("stripe unavailable")).Once()
+
+	svc := service.NewPlanService(catalog, mockPay)
+	_, err := svc.SyncPlans(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, newCorePlan(), catalog["Core"])
+}
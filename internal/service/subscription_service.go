@@ -2,26 +2,51 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"github.com/DenisBarabanshchikov/subscription/internal/model"
 	"github.com/DenisBarabanshchikov/subscription/internal/port"
 	"github.com/DenisBarabanshchikov/subscription/pkg/uuid"
+	"github.com/pkg/errors"
+	"log"
+	"time"
 )
 
 type SubscriptionService interface {
 	CreateCustomer(ctx context.Context, customerEmail string) (model.Customer, error)
-	SubscriberCustomer(ctx context.Context, customerId, plan string) (model.Subscription, error)
+	SubscriberCustomer(ctx context.Context, customerId, plan string, interval model.BillingInterval, currency string, quantity int, trial *model.Trial) (model.Subscription, error)
 	SubscriptionStatus(ctx context.Context, customerId, subscriptionId string) (model.Subscription, error)
+	SubscriptionHistory(ctx context.Context, customerId, subscriptionId string) ([]model.StatusChange, error)
+	CancelSubscription(ctx context.Context, customerId, subscriptionId string, cancellation model.Cancellation) (model.Subscription, error)
+	PauseSubscription(ctx context.Context, customerId, subscriptionId string, pause model.Pause) (model.Subscription, error)
+	ResumeSubscription(ctx context.Context, customerId, subscriptionId string) (model.Subscription, error)
+	ChangePlan(ctx context.Context, customerId, subscriptionId string, change model.PlanChange) (model.Subscription, error)
+	PreviewPlanChange(ctx context.Context, customerId, subscriptionId string, change model.PlanChange) (model.InvoicePreview, error)
+	CreateCheckoutSession(ctx context.Context, customerId string, checkout model.Checkout) (model.CheckoutSession, error)
+	CreatePortalSession(ctx context.Context, customerId string) (string, error)
+	CreateSetupIntent(ctx context.Context, customerId string) (model.PaymentConfirmation, error)
+	PaymentMethods(ctx context.Context, customerId string) ([]model.PaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, customerId, paymentMethodId string) ([]model.PaymentMethod, error)
+	DetachPaymentMethod(ctx context.Context, customerId, paymentMethodId string) ([]model.PaymentMethod, error)
+	ChangeQuantity(ctx context.Context, customerId, subscriptionId string, change model.QuantityChange) (model.Subscription, error)
+	AddOns(ctx context.Context, customerId, subscriptionId string) ([]model.AddOn, error)
+	AddAddOn(ctx context.Context, customerId, subscriptionId, addOn string, quantity int) (model.Subscription, error)
+	RemoveAddOn(ctx context.Context, customerId, subscriptionId, addOn string) (model.Subscription, error)
 }
 
+// maxTrialDays is the longest trial the payment provider accepts
+const maxTrialDays = 730
+
 type subscriptionService struct {
 	customer        port.Subscription
 	paymentProvider port.PaymentProvider
+	plans           port.Plan
 }
 
-func NewSubscriptionService(customer port.Subscription, paymentProvider port.PaymentProvider) SubscriptionService {
+func NewSubscriptionService(customer port.Subscription, paymentProvider port.PaymentProvider, plans port.Plan) SubscriptionService {
 	return &subscriptionService{
 		customer:        customer,
 		paymentProvider: paymentProvider,
+		plans:           plans,
 	}
 }
 
@@ -42,7 +67,19 @@ func (s subscriptionService) CreateCustomer(ctx context.Context, customerEmail s
 	return customer, nil
 }
 
-func (s subscriptionService) SubscriberCustomer(ctx context.Context, customerId, plan string) (model.Subscription, error) {
+// SubscriberCustomer bills the customer in their currency, the first subscription of a customer sets it
+func (s subscriptionService) SubscriberCustomer(ctx context.Context, customerId, plan string, interval model.BillingInterval, currency string, quantity int, trial *model.Trial) (model.Subscription, error) {
+	if quantity < 1 {
+		return model.Subscription{}, model.NewValidationErr("quantity must be at least 1")
+	}
+	if !interval.IsValid() {
+		return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("unknown billing interval '%s'", interval))
+	}
+	trial, err := s.resolveTrial(ctx, plan, trial)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
 	customer, err := s.customer.GetCustomer(context.Background(), customerId)
 	if err != nil {
 		return model.Subscription{}, err
@@ -50,25 +87,140 @@ func (s subscriptionService) SubscriberCustomer(ctx context.Context, customerId,
 	if customer == nil {
 		return model.Subscription{}, model.NewCustomerNotFoundErr(customerId)
 	}
-	subscriptionId, err := s.paymentProvider.SubscribeCustomer(ctx, *customer, plan)
+	err = s.fillCustomerCurrency(ctx, customer)
 	if err != nil {
 		return model.Subscription{}, err
 	}
+	currency, err = customerCurrency(*customer, currency)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	created, err := s.paymentProvider.SubscribeCustomer(ctx, *customer, plan, interval, currency, quantity, trial)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	// Without a trial the subscription stays incomplete until the first payment
 	subscription := model.Subscription{
 		SubscriptionId:         uuid.GenerateUUID(),
 		CustomerId:             customer.CustomerId,
-		ExternalSubscriptionID: subscriptionId,
+		ExternalSubscriptionID: created.ExternalSubscriptionId,
 		Plan:                   plan,
-		Status:                 "new",
+		Interval:               interval,
+		Currency:               created.Currency,
+		Quantity:               quantity,
+		Status:                 created.Status,
+		TrialEnd:               created.TrialEnd,
 	}
 	err = s.customer.CreateSubscription(ctx, subscription)
 	if err != nil {
 		return model.Subscription{}, err
 	}
+	if customer.Currency == "" && created.Currency != "" {
+		err = s.customer.SetCustomerCurrency(ctx, customer.CustomerId, created.Currency)
+		if err != nil {
+			return model.Subscription{}, err
+		}
+	}
+	subscription.Payment = created.Payment
 
 	return subscription, nil
 }
 
+// CreateCheckoutSession leaves recording the subscription and the currency of the customer to the webhook
+// of the completed session
+func (s subscriptionService) CreateCheckoutSession(ctx context.Context, customerId string, checkout model.Checkout) (model.CheckoutSession, error) {
+	if checkout.Plan == "" {
+		return model.CheckoutSession{}, model.NewValidationErr("plan is required")
+	}
+	if !checkout.Interval.IsValid() {
+		return model.CheckoutSession{}, model.NewValidationErr(fmt.Sprintf("unknown billing interval '%s'", checkout.Interval))
+	}
+
+	customer, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return model.CheckoutSession{}, err
+	}
+	err = s.fillCustomerCurrency(ctx, customer)
+	if err != nil {
+		return model.CheckoutSession{}, err
+	}
+	checkout.Currency, err = customerCurrency(*customer, checkout.Currency)
+	if err != nil {
+		return model.CheckoutSession{}, err
+	}
+
+	return s.paymentProvider.CreateCheckoutSession(ctx, *customer, checkout)
+}
+
+// CreatePortalSession returns the url of the page where the customer manages payment methods and invoices
+func (s subscriptionService) CreatePortalSession(ctx context.Context, customerId string) (string, error) {
+	customer, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return "", err
+	}
+
+	return s.paymentProvider.CreatePortalSession(ctx, *customer)
+}
+
+// CreateSetupIntent lets the customer save a payment method before subscribing
+func (s subscriptionService) CreateSetupIntent(ctx context.Context, customerId string) (model.PaymentConfirmation, error) {
+	customer, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return model.PaymentConfirmation{}, err
+	}
+
+	return s.paymentProvider.CreateSetupIntent(ctx, *customer)
+}
+
+func (s subscriptionService) PaymentMethods(ctx context.Context, customerId string) ([]model.PaymentMethod, error) {
+	customer, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.paymentProvider.PaymentMethods(ctx, *customer)
+}
+
+// SetDefaultPaymentMethod returns the payment methods of the customer with the new default
+func (s subscriptionService) SetDefaultPaymentMethod(ctx context.Context, customerId, paymentMethodId string) ([]model.PaymentMethod, error) {
+	customer, methods, err := s.findPaymentMethod(ctx, customerId, paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.paymentProvider.SetDefaultPaymentMethod(ctx, *customer, paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range methods {
+		methods[i].Default = methods[i].ExternalPaymentMethodId == paymentMethodId
+	}
+
+	return methods, nil
+}
+
+// DetachPaymentMethod returns the payment methods the customer has left. Detaching the default
+// leaves the customer without one.
+func (s subscriptionService) DetachPaymentMethod(ctx context.Context, customerId, paymentMethodId string) ([]model.PaymentMethod, error) {
+	_, methods, err := s.findPaymentMethod(ctx, customerId, paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.paymentProvider.DetachPaymentMethod(ctx, paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.PaymentMethod, 0, len(methods))
+	for _, method := range methods {
+		if method.ExternalPaymentMethodId != paymentMethodId {
+			res = append(res, method)
+		}
+	}
+
+	return res, nil
+}
+
 func (s subscriptionService) SubscriptionStatus(ctx context.Context, customerId, subscriptionId string) (model.Subscription, error) {
 	customer, err := s.customer.GetCustomer(context.Background(), customerId)
 	if err != nil {
@@ -89,9 +241,445 @@ func (s subscriptionService) SubscriptionStatus(ctx context.Context, customerId,
 	if err != nil {
 		return model.Subscription{}, err
 	}
-	subscription.Status = status
+	if status == subscription.Status {
+		return *subscription, nil
+	}
+	if !subscription.Status.CanTransitionTo(status) {
+		// The read keeps the stored status, the webhook event of the change ends up in review
+		log.Printf("keeping subscription '%s' %s: %v", subscription.SubscriptionId, subscription.Status,
+			model.NewIllegalStatusTransitionErr(subscription.SubscriptionId, subscription.Status, status))
+		return *subscription, nil
+	}
+
+	return s.storeProviderStatus(ctx, *subscription, status, model.StatusChangeSourceReconciliation)
+}
+
+func (s subscriptionService) SubscriptionHistory(ctx context.Context, customerId, subscriptionId string) ([]model.StatusChange, error) {
+	if _, err := s.findSubscription(ctx, customerId, subscriptionId); err != nil {
+		return nil, err
+	}
+
+	return s.customer.GetStatusHistory(ctx, customerId, subscriptionId)
+}
+
+func (s subscriptionService) CancelSubscription(ctx context.Context, customerId, subscriptionId string, cancellation model.Cancellation) (model.Subscription, error) {
+	if cancellation.Mode != model.CancelModeImmediately && cancellation.Mode != model.CancelModeAtPeriodEnd {
+		return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("unknown cancel mode '%s'", cancellation.Mode))
+	}
+
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status == model.SubscriptionStatusCanceled || !subscription.Status.CanTransitionTo(model.SubscriptionStatusCanceled) {
+		return model.Subscription{}, model.NewIllegalStatusTransitionErr(subscription.SubscriptionId, subscription.Status, model.SubscriptionStatusCanceled)
+	}
+
+	status, err := s.paymentProvider.CancelSubscription(ctx, subscription.ExternalSubscriptionID, cancellation)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	subscription.CancelAtPeriodEnd = cancellation.Mode == model.CancelModeAtPeriodEnd
+	return s.storeProviderStatus(ctx, *subscription, status, model.StatusChangeSourceApi)
+}
+
+func (s subscriptionService) PauseSubscription(ctx context.Context, customerId, subscriptionId string, pause model.Pause) (model.Subscription, error) {
+	if !pause.Behavior.IsValid() {
+		return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("unknown pause behavior '%s'", pause.Behavior))
+	}
+	if pause.ResumesAt != nil && !pause.ResumesAt.After(time.Now()) {
+		return model.Subscription{}, model.NewValidationErr("resume date must be in the future")
+	}
 
-	//TODO update status in DB if this is different
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status.IsEnded() {
+		return model.Subscription{}, model.NewSubscriptionEndedErr(subscription.SubscriptionId, subscription.Status)
+	}
+	if !subscription.Status.CanTransitionTo(model.SubscriptionStatusPaused) {
+		return model.Subscription{}, model.NewIllegalStatusTransitionErr(subscription.SubscriptionId, subscription.Status, model.SubscriptionStatusPaused)
+	}
+
+	status, err := s.paymentProvider.PauseSubscription(ctx, subscription.ExternalSubscriptionID, pause)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return s.storeProviderStatus(ctx, *subscription, status, model.StatusChangeSourceApi)
+}
+
+func (s subscriptionService) ResumeSubscription(ctx context.Context, customerId, subscriptionId string) (model.Subscription, error) {
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status != model.SubscriptionStatusPaused {
+		return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("subscription '%s' is not paused", subscription.SubscriptionId))
+	}
+
+	// Whatever the provider resumed the subscription to is stored, it has already happened there
+	status, err := s.paymentProvider.ResumeSubscription(ctx, subscription.ExternalSubscriptionID)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return s.storeProviderStatus(ctx, *subscription, status, model.StatusChangeSourceApi)
+}
+
+// ChangePlan also switches the billing interval, on the same plan as well
+func (s subscriptionService) ChangePlan(ctx context.Context, customerId, subscriptionId string, change model.PlanChange) (model.Subscription, error) {
+	subscription, err := s.findChangeableSubscription(ctx, customerId, subscriptionId, &change)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Plan == change.Plan && subscription.Interval == change.Interval {
+		return *subscription, nil
+	}
+
+	err = s.paymentProvider.ChangePlan(ctx, subscription.ExternalSubscriptionID, change)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	subscription.Plan = change.Plan
+	subscription.Interval = change.Interval
+	err = s.customer.UpdateSubscriptionPlan(ctx, *subscription)
+	if err != nil {
+		return model.Subscription{}, err
+	}
 
 	return *subscription, nil
 }
+
+func (s subscriptionService) ChangeQuantity(ctx context.Context, customerId, subscriptionId string, change model.QuantityChange) (model.Subscription, error) {
+	if change.Quantity < 1 {
+		return model.Subscription{}, model.NewValidationErr("quantity must be at least 1")
+	}
+	if !change.ProrationBehavior.IsValid() {
+		return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("unknown proration behavior '%s'", change.ProrationBehavior))
+	}
+
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status.IsEnded() {
+		return model.Subscription{}, model.NewSubscriptionEndedErr(subscription.SubscriptionId, subscription.Status)
+	}
+	if subscription.Quantity == change.Quantity {
+		return *subscription, nil
+	}
+
+	err = s.paymentProvider.ChangeQuantity(ctx, subscription.ExternalSubscriptionID, change)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	subscription.Quantity = change.Quantity
+	err = s.customer.UpdateSubscriptionQuantity(ctx, *subscription)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return *subscription, nil
+}
+
+func (s subscriptionService) AddOns(ctx context.Context, customerId, subscriptionId string) ([]model.AddOn, error) {
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	return subscription.AddOns, nil
+}
+
+func (s subscriptionService) AddAddOn(ctx context.Context, customerId, subscriptionId, addOn string, quantity int) (model.Subscription, error) {
+	if addOn == "" {
+		return model.Subscription{}, model.NewValidationErr("add-on is required")
+	}
+	if quantity < 1 {
+		return model.Subscription{}, model.NewValidationErr("quantity must be at least 1")
+	}
+
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status.IsEnded() {
+		return model.Subscription{}, model.NewSubscriptionEndedErr(subscription.SubscriptionId, subscription.Status)
+	}
+	for _, existing := range subscription.AddOns {
+		if existing.Name == addOn {
+			return model.Subscription{}, model.NewValidationErr(fmt.Sprintf("subscription '%s' already has add-on '%s'", subscription.SubscriptionId, addOn))
+		}
+	}
+
+	currency, err := s.subscriptionCurrency(ctx, subscription)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	itemId, err := s.paymentProvider.AddAddOn(ctx, subscription.ExternalSubscriptionID, addOn, currency, quantity)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	subscription.AddOns = append(subscription.AddOns, model.AddOn{
+		Name:           addOn,
+		ExternalItemId: itemId,
+		Quantity:       quantity,
+	})
+	err = s.customer.UpdateSubscriptionAddOns(ctx, *subscription)
+	if err != nil {
+		// The item would be billed without being stored, so it is deleted again
+		if removeErr := s.paymentProvider.RemoveAddOn(ctx, itemId); removeErr != nil {
+			log.Printf("failed to delete add-on item '%s' of subscription '%s' which was not stored: %v", itemId, subscription.SubscriptionId, removeErr)
+		}
+		return model.Subscription{}, err
+	}
+
+	return *subscription, nil
+}
+
+func (s subscriptionService) RemoveAddOn(ctx context.Context, customerId, subscriptionId, addOn string) (model.Subscription, error) {
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if subscription.Status.IsEnded() {
+		return model.Subscription{}, model.NewSubscriptionEndedErr(subscription.SubscriptionId, subscription.Status)
+	}
+
+	index := -1
+	for i, existing := range subscription.AddOns {
+		if existing.Name == addOn {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return model.Subscription{}, model.NewAddOnNotFoundErr(subscription.SubscriptionId, addOn)
+	}
+
+	err = s.paymentProvider.RemoveAddOn(ctx, subscription.AddOns[index].ExternalItemId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	subscription.AddOns = append(subscription.AddOns[:index], subscription.AddOns[index+1:]...)
+	err = s.customer.UpdateSubscriptionAddOns(ctx, *subscription)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return *subscription, nil
+}
+
+// PreviewPlanChange returns the next invoice as it would be after the plan change, nothing is changed
+func (s subscriptionService) PreviewPlanChange(ctx context.Context, customerId, subscriptionId string, change model.PlanChange) (model.InvoicePreview, error) {
+	subscription, err := s.findChangeableSubscription(ctx, customerId, subscriptionId, &change)
+	if err != nil {
+		return model.InvoicePreview{}, err
+	}
+
+	return s.paymentProvider.PreviewPlanChange(ctx, subscription.ExternalSubscriptionID, change)
+}
+
+// resolveTrial validates the requested trial and replaces a plan default trial by its length from the catalog
+func (s subscriptionService) resolveTrial(ctx context.Context, plan string, trial *model.Trial) (*model.Trial, error) {
+	if trial == nil {
+		return nil, nil
+	}
+	if !trial.EndBehavior.IsValid() {
+		return nil, model.NewValidationErr(fmt.Sprintf("unknown trial end behavior '%s'", trial.EndBehavior))
+	}
+
+	res := *trial
+	if trial.PlanDefault {
+		if trial.Days != 0 {
+			return nil, model.NewValidationErr("trial days can't be combined with the plan default trial")
+		}
+		catalogPlan, err := s.plans.GetPlan(ctx, plan)
+		if err != nil {
+			return nil, err
+		}
+		if catalogPlan == nil || catalogPlan.TrialDays <= 0 {
+			return nil, model.NewValidationErr(fmt.Sprintf("plan '%s' has no default trial", plan))
+		}
+		res.Days = catalogPlan.TrialDays
+		res.PlanDefault = false
+	}
+	if res.Days <= 0 || res.Days > maxTrialDays {
+		return nil, model.NewValidationErr(fmt.Sprintf("trial days must be between 1 and %d", maxTrialDays))
+	}
+	return &res, nil
+}
+
+// storeProviderStatus stores the status just read from the payment provider. LastEventAt is left as it
+// is, it only moves with provider events, so the write is stale if an event was synced since the
+// subscription was read. The subscription as stored by that event is returned then.
+func (s subscriptionService) storeProviderStatus(ctx context.Context, subscription model.Subscription, status model.SubscriptionStatus, source model.StatusChangeSource) (model.Subscription, error) {
+	syncedAt := time.Now()
+	change := newStatusChange(subscription.Status, status, source, "")
+	subscription.Status = status
+	subscription.SyncedAt = &syncedAt
+	err := s.customer.UpdateSubscriptionStatus(ctx, subscription, change)
+	var staleErr model.StaleSubscriptionUpdateErr
+	if errors.As(err, &staleErr) {
+		return s.findSubscriptionOf(ctx, subscription)
+	}
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	return subscription, nil
+}
+
+func (s subscriptionService) findSubscriptionOf(ctx context.Context, subscription model.Subscription) (model.Subscription, error) {
+	stored, err := s.customer.GetSubscription(ctx, subscription.CustomerId, subscription.SubscriptionId)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if stored == nil {
+		return model.Subscription{}, model.NewSubscriptionNotFoundErr(subscription.SubscriptionId)
+	}
+	return *stored, nil
+}
+
+// findChangeableSubscription validates the plan change and returns the subscription if it has not ended yet.
+// A change without an interval gets the current one of the subscription, and the change is billed in the
+// currency of the subscription.
+func (s subscriptionService) findChangeableSubscription(ctx context.Context, customerId, subscriptionId string, change *model.PlanChange) (*model.Subscription, error) {
+	if change.Plan == "" {
+		return nil, model.NewValidationErr("plan is required")
+	}
+	if change.Interval != "" && !change.Interval.IsValid() {
+		return nil, model.NewValidationErr(fmt.Sprintf("unknown billing interval '%s'", change.Interval))
+	}
+	if !change.ProrationBehavior.IsValid() {
+		return nil, model.NewValidationErr(fmt.Sprintf("unknown proration behavior '%s'", change.ProrationBehavior))
+	}
+
+	subscription, err := s.findSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status.IsEnded() {
+		return nil, model.NewSubscriptionEndedErr(subscription.SubscriptionId, subscription.Status)
+	}
+	if change.Interval == "" {
+		change.Interval = subscription.Interval
+	}
+	change.Currency, err = s.subscriptionCurrency(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// subscriptionCurrency returns the currency the subscription is billed in. Subscriptions stored before
+// their currency was recorded have it read from the payment provider.
+func (s subscriptionService) subscriptionCurrency(ctx context.Context, subscription *model.Subscription) (string, error) {
+	if subscription.Currency != "" {
+		return subscription.Currency, nil
+	}
+	return s.paymentProvider.GetSubscriptionCurrency(ctx, subscription.ExternalSubscriptionID)
+}
+
+// fillCustomerCurrency sets the currency of a customer stored before currencies were recorded from one of
+// their subscriptions, Stripe bills the customer in that currency already
+func (s subscriptionService) fillCustomerCurrency(ctx context.Context, customer *model.Customer) error {
+	if customer.Currency != "" {
+		return nil
+	}
+	subscriptions, err := s.customer.GetSubscriptions(ctx, customer.CustomerId)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+	currency, err := s.subscriptionCurrency(ctx, &subscriptions[0])
+	if err != nil || currency == "" {
+		return err
+	}
+	err = s.customer.SetCustomerCurrency(ctx, customer.CustomerId, currency)
+	if err != nil {
+		return err
+	}
+	customer.Currency = currency
+	return nil
+}
+
+// findPaymentMethod also returns all payment methods of the customer. A method of another
+// customer is not found, so customers can't change each other's payment methods.
+func (s subscriptionService) findPaymentMethod(ctx context.Context, customerId, paymentMethodId string) (*model.Customer, []model.PaymentMethod, error) {
+	customer, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return nil, nil, err
+	}
+	methods, err := s.paymentProvider.PaymentMethods(ctx, *customer)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, method := range methods {
+		if method.ExternalPaymentMethodId == paymentMethodId {
+			return customer, methods, nil
+		}
+	}
+	return nil, nil, model.NewPaymentMethodNotFoundErr(customerId, paymentMethodId)
+}
+
+// findCustomer returns the customer or a not found error
+func (s subscriptionService) findCustomer(ctx context.Context, customerId string) (*model.Customer, error) {
+	customer, err := s.customer.GetCustomer(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, model.NewCustomerNotFoundErr(customerId)
+	}
+	return customer, nil
+}
+
+// findSubscription returns the subscription of the customer or a not found error for either of them
+func (s subscriptionService) findSubscription(ctx context.Context, customerId, subscriptionId string) (*model.Subscription, error) {
+	_, err := s.findCustomer(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.customer.GetSubscription(ctx, customerId, subscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, model.NewSubscriptionNotFoundErr(subscriptionId)
+	}
+	return subscription, nil
+}
+
+// customerCurrency returns the currency to bill the customer in. A customer who has a currency can't be
+// billed in another one, without either the payment provider bills in the main currency of the plan.
+func customerCurrency(customer model.Customer, currency string) (string, error) {
+	if currency != "" && !currencyPattern.MatchString(currency) {
+		return "", model.NewValidationErr(fmt.Sprintf("currency '%s' is not a lowercase ISO 4217 code", currency))
+	}
+	if customer.Currency == "" {
+		return currency, nil
+	}
+	if currency != "" && currency != customer.Currency {
+		return "", model.NewCurrencyMismatchErr(customer.CustomerId, currency)
+	}
+	return customer.Currency, nil
+}
+
+// newStatusChange returns the history entry of a status change, or nil if the status stays the same
+func newStatusChange(previous, status model.SubscriptionStatus, source model.StatusChangeSource, eventId string) *model.StatusChange {
+	if previous == status {
+		return nil
+	}
+	return &model.StatusChange{
+		PreviousStatus: previous,
+		Status:         status,
+		Source:         source,
+		EventId:        eventId,
+		ChangedAt:      time.Now(),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -47,6 +48,107 @@ func (m *mockSubscription) GetSubscription(ctx context.Context, customerId, subs
 	return nil, args.Error(1)
 }
 
+func (m *mockSubscription) SetCustomerCurrency(ctx context.Context, customerId, currency string) error {
+	args := m.Called(ctx, customerId, currency)
+	return args.Error(0)
+}
+
+func (m *mockSubscription) GetCustomerByExternalId(ctx context.Context, externalCustomerId string) (*model.Customer, error) {
+	args := m.Called(ctx, externalCustomerId)
+	if cust, ok := args.Get(0).(*model.Customer); ok {
+		return cust, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSubscription) GetSubscriptions(ctx context.Context, customerId string) ([]model.Subscription, error) {
+	args := m.Called(ctx, customerId)
+	if subs, ok := args.Get(0).([]model.Subscription); ok {
+		return subs, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSubscription) GetSubscriptionByExternalId(ctx context.Context, externalSubscriptionId string) (*model.Subscription, error) {
+	args := m.Called(ctx, externalSubscriptionId)
+	if sub, ok := args.Get(0).(*model.Subscription); ok {
+		return sub, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockSubscription) UpdateSubscriptionStatus(ctx context.Context, subscription model.Subscription, change *model.StatusChange) error {
+	args := m.Called(ctx, subscription, change)
+	return args.Error(0)
+}
+
+func (m *mockSubscription) UpdateSubscriptionPlan(ctx context.Context, subscription model.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *mockSubscription) UpdateSubscriptionQuantity(ctx context.Context, subscription model.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *mockSubscription) UpdateSubscriptionAddOns(ctx context.Context, subscription model.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *mockSubscription) GetStatusHistory(ctx context.Context, customerId, subscriptionId string) ([]model.StatusChange, error) {
+	args := m.Called(ctx, customerId, subscriptionId)
+	if history, ok := args.Get(0).([]model.StatusChange); ok {
+		return history, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// mockEvent implements port.Event.
+type mockEvent struct {
+	mock.Mock
+}
+
+func (m *mockEvent) CreateEvent(ctx context.Context, event model.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *mockEvent) GetEvent(ctx context.Context, eventId string) (*model.Event, error) {
+	args := m.Called(ctx, eventId)
+	if evt, ok := args.Get(0).(*model.Event); ok {
+		return evt, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockEvent) UpdateEvent(ctx context.Context, event model.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *mockEvent) GetEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error) {
+	args := m.Called(ctx, filter)
+	if evts, ok := args.Get(0).([]model.Event); ok {
+		return evts, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockEvent) GetDueEvents(ctx context.Context, before time.Time, limit int) ([]model.Event, error) {
+	args := m.Called(ctx, before, limit)
+	if evts, ok := args.Get(0).([]model.Event); ok {
+		return evts, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockEvent) ClaimEvent(ctx context.Context, event model.Event, until time.Time) (bool, error) {
+	args := m.Called(ctx, event, until)
+	return args.Bool(0), args.Error(1)
+}
+
 // mockPaymentProvider implements port.PaymentProvider.
 type mockPaymentProvider struct {
 	mock.Mock
@@ -57,18 +159,159 @@ func (m *mockPaymentProvider) CreateCustomer(ctx context.Context, email string)
 	return args.String(0), args.Error(1)
 }
 
-func (m *mockPaymentProvider) SubscribeCustomer(ctx context.Context, customer model.Customer, plan string) (string, error) {
-	args := m.Called(ctx, customer, plan)
+func (m *mockPaymentProvider) SubscribeCustomer(ctx context.Context, customer model.Customer, plan string, interval model.BillingInterval, currency string, quantity int, trial *model.Trial) (model.ProviderSubscription, error) {
+	args := m.Called(ctx, customer, plan, interval, currency, quantity, trial)
+	return args.Get(0).(model.ProviderSubscription), args.Error(1)
+}
+
+func (m *mockPaymentProvider) GetSubscriptionCurrency(ctx context.Context, subscriptionId string) (string, error) {
+	args := m.Called(ctx, subscriptionId)
 	return args.String(0), args.Error(1)
 }
 
-func (m *mockPaymentProvider) GetSubscriptionStatus(ctx context.Context, subscriptionId string) (string, error) {
+func (m *mockPaymentProvider) GetSubscriptionStatus(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error) {
+	args := m.Called(ctx, subscriptionId)
+	status, _ := args.Get(0).(model.SubscriptionStatus)
+	return status, args.Error(1)
+}
+
+func (m *mockPaymentProvider) CancelSubscription(ctx context.Context, subscriptionId string, cancellation model.Cancellation) (model.SubscriptionStatus, error) {
+	args := m.Called(ctx, subscriptionId, cancellation)
+	status, _ := args.Get(0).(model.SubscriptionStatus)
+	return status, args.Error(1)
+}
+
+func (m *mockPaymentProvider) PauseSubscription(ctx context.Context, subscriptionId string, pause model.Pause) (model.SubscriptionStatus, error) {
+	args := m.Called(ctx, subscriptionId, pause)
+	status, _ := args.Get(0).(model.SubscriptionStatus)
+	return status, args.Error(1)
+}
+
+func (m *mockPaymentProvider) ResumeSubscription(ctx context.Context, subscriptionId string) (model.SubscriptionStatus, error) {
 	args := m.Called(ctx, subscriptionId)
+	status, _ := args.Get(0).(model.SubscriptionStatus)
+	return status, args.Error(1)
+}
+
+func (m *mockPaymentProvider) ChangePlan(ctx context.Context, subscriptionId string, change model.PlanChange) error {
+	args := m.Called(ctx, subscriptionId, change)
+	return args.Error(0)
+}
+
+func (m *mockPaymentProvider) ChangeQuantity(ctx context.Context, subscriptionId string, change model.QuantityChange) error {
+	args := m.Called(ctx, subscriptionId, change)
+	return args.Error(0)
+}
+
+func (m *mockPaymentProvider) AddAddOn(ctx context.Context, subscriptionId, addOn, currency string, quantity int) (string, error) {
+	args := m.Called(ctx, subscriptionId, addOn, currency, quantity)
 	return args.String(0), args.Error(1)
 }
 
+func (m *mockPaymentProvider) RemoveAddOn(ctx context.Context, externalItemId string) error {
+	args := m.Called(ctx, externalItemId)
+	return args.Error(0)
+}
+
+func (m *mockPaymentProvider) CreateCheckoutSession(ctx context.Context, customer model.Customer, checkout model.Checkout) (model.CheckoutSession, error) {
+	args := m.Called(ctx, customer, checkout)
+	return args.Get(0).(model.CheckoutSession), args.Error(1)
+}
+
+func (m *mockPaymentProvider) CreatePortalSession(ctx context.Context, customer model.Customer) (string, error) {
+	args := m.Called(ctx, customer)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockPaymentProvider) CreateSetupIntent(ctx context.Context, customer model.Customer) (model.PaymentConfirmation, error) {
+	args := m.Called(ctx, customer)
+	return args.Get(0).(model.PaymentConfirmation), args.Error(1)
+}
+
+func (m *mockPaymentProvider) PaymentMethods(ctx context.Context, customer model.Customer) ([]model.PaymentMethod, error) {
+	args := m.Called(ctx, customer)
+	return args.Get(0).([]model.PaymentMethod), args.Error(1)
+}
+
+func (m *mockPaymentProvider) SetDefaultPaymentMethod(ctx context.Context, customer model.Customer, paymentMethodId string) error {
+	args := m.Called(ctx, customer, paymentMethodId)
+	return args.Error(0)
+}
+
+func (m *mockPaymentProvider) DetachPaymentMethod(ctx context.Context, paymentMethodId string) error {
+	args := m.Called(ctx, paymentMethodId)
+	return args.Error(0)
+}
+
+func (m *mockPaymentProvider) GetPlanCatalog(ctx context.Context) (model.ProviderCatalog, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(model.ProviderCatalog), args.Error(1)
+}
+
+func (m *mockPaymentProvider) PreviewPlanChange(ctx context.Context, subscriptionId string, change model.PlanChange) (model.InvoicePreview, error) {
+	args := m.Called(ctx, subscriptionId, change)
+	return args.Get(0).(model.InvoicePreview), args.Error(1)
+}
+
+func (m *mockPaymentProvider) ConstructEvent(ctx context.Context, payload []byte, signature string) (model.Event, error) {
+	args := m.Called(ctx, payload, signature)
+	return args.Get(0).(model.Event), args.Error(1)
+}
+
+func (m *mockPaymentProvider) ParseEvent(ctx context.Context, payload []byte) (model.Event, error) {
+	args := m.Called(ctx, payload)
+	return args.Get(0).(model.Event), args.Error(1)
+}
+
+// planCatalog implements port.Plan in memory.
+type planCatalog map[string]model.Plan
+
+func (c planCatalog) GetPlan(_ context.Context, planId string) (*model.Plan, error) {
+	plan, ok := c[planId]
+	if !ok {
+		return nil, nil
+	}
+	return &plan, nil
+}
+
+func (c planCatalog) GetPlans(_ context.Context) ([]model.Plan, error) {
+	res := make([]model.Plan, 0, len(c))
+	for _, plan := range c {
+		res = append(res, plan)
+	}
+	return res, nil
+}
+
+func (c planCatalog) CreatePlan(_ context.Context, plan model.Plan) error {
+	if _, ok := c[plan.PlanId]; ok {
+		return model.NewPlanAlreadyExistsErr(plan.PlanId)
+	}
+	c[plan.PlanId] = plan
+	return nil
+}
+
+func (c planCatalog) PutPlan(_ context.Context, plan model.Plan) error {
+	stored, ok := c[plan.PlanId]
+	if !ok {
+		return model.NewPlanNotFoundErr(plan.PlanId)
+	}
+	if stored.Version != plan.Version {
+		return model.NewStalePlanUpdateErr(plan.PlanId)
+	}
+	plan.Version++
+	c[plan.PlanId] = plan
+	return nil
+}
+
 // --- Unit Tests ---
 
+// plans gives only the Core plan a default trial
+var plans = planCatalog{
+	"Core":    {PlanId: "Core", TrialDays: 14},
+	"Growth":  {PlanId: "Growth"},
+	"Premium": {PlanId: "Premium"},
+}
+
 func TestCreateCustomer_Success(t *testing.T) {
 	ctx := context.Background()
 
@@ -92,7 +335,7 @@ func TestCreateCustomer_Success(t *testing.T) {
 		})).
 		Return(nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	cust, err := svc.CreateCustomer(ctx, email)
 	assert.NoError(t, err)
 	assert.Equal(t, externalCustomerID, cust.ExternalCustomerId)
@@ -115,7 +358,7 @@ func TestCreateCustomer_PaymentProviderError(t *testing.T) {
 		On("CreateCustomer", ctx, email).
 		Return("", expectedErr).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	cust, err := svc.CreateCustomer(ctx, email)
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
@@ -145,10 +388,20 @@ func TestSubscriberCustomer_Success(t *testing.T) {
 		On("GetCustomer", mock.Anything, customerId).
 		Return(existingCustomer, nil).Once()
 
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, customerId).
+		Return([]model.Subscription{}, nil).Once()
+
+	payment := &model.PaymentConfirmation{
+		Intent:       model.PaymentIntentTypePayment,
+		ClientSecret: "pi_123_secret_456",
+		Status:       "requires_payment_method",
+	}
 	// Expect the payment provider to subscribe the customer.
 	mockPay.
-		On("SubscribeCustomer", ctx, *existingCustomer, plan).
-		Return(externalSubID, nil).Once()
+		On("SubscribeCustomer", ctx, *existingCustomer, plan, model.BillingIntervalYear, "", 1, (*model.Trial)(nil)).
+		Return(model.ProviderSubscription{ExternalSubscriptionId: externalSubID, Status: model.SubscriptionStatusIncomplete, Currency: "usd", Payment: payment}, nil).Once()
 
 	// Expect CreateSubscription to be called with a subscription that has the proper fields.
 	mockSub.
@@ -156,24 +409,210 @@ func TestSubscriberCustomer_Success(t *testing.T) {
 			return s.CustomerId == customerId &&
 				s.ExternalSubscriptionID == externalSubID &&
 				s.Plan == plan &&
-				s.Status == "new" &&
-				s.SubscriptionId != ""
+				s.Interval == model.BillingIntervalYear &&
+				s.Currency == "usd" &&
+				s.Status == model.SubscriptionStatusIncomplete &&
+				s.SubscriptionId != "" &&
+				s.Payment == nil
 		})).
 		Return(nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
-	sub, err := svc.SubscriberCustomer(ctx, customerId, plan)
+	// The first subscription sets the currency of the customer.
+	mockSub.
+		On("SetCustomerCurrency", ctx, customerId, "usd").
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriberCustomer(ctx, customerId, plan, model.BillingIntervalYear, "", 1, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, customerId, sub.CustomerId)
 	assert.Equal(t, externalSubID, sub.ExternalSubscriptionID)
 	assert.Equal(t, plan, sub.Plan)
-	assert.Equal(t, "new", sub.Status)
+	assert.Equal(t, model.BillingIntervalYear, sub.Interval)
+	assert.Equal(t, model.SubscriptionStatusIncomplete, sub.Status)
 	assert.NotEmpty(t, sub.SubscriptionId)
+	assert.Equal(t, payment, sub.Payment)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestSubscriberCustomer_CustomerCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123", Currency: "eur"}
+	mockSub.
+		On("GetCustomer", mock.Anything, "cust_123").
+		Return(customer, nil).Once()
+
+	// Without a currency the customer is billed in theirs
+	mockPay.
+		On("SubscribeCustomer", ctx, *customer, "Core", model.BillingIntervalMonth, "eur", 1, (*model.Trial)(nil)).
+		Return(model.ProviderSubscription{ExternalSubscriptionId: "ext_sub_456", Status: model.SubscriptionStatusIncomplete, Currency: "eur"}, nil).Once()
+
+	mockSub.
+		On("CreateSubscription", ctx, mock.Anything).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "", 1, nil)
+	assert.NoError(t, err)
+
+	mockSub.AssertNotCalled(t, "SetCustomerCurrency", mock.Anything, mock.Anything, mock.Anything)
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+// TestSubscriberCustomer_LegacyCustomerCurrency takes the currency of a customer stored before currencies
+// were recorded from their existing subscription
+func TestSubscriberCustomer_LegacyCustomerCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", mock.Anything, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return([]model.Subscription{{SubscriptionId: "sub_old", ExternalSubscriptionID: "ext_sub_old", Status: model.SubscriptionStatusActive}}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionCurrency", ctx, "ext_sub_old").
+		Return("eur", nil).Once()
+
+	mockSub.
+		On("SetCustomerCurrency", ctx, "cust_123", "eur").
+		Return(nil).Once()
+
+	// The customer is billed in the currency of the existing subscription, not the main one of the plan
+	customer := model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123", Currency: "eur"}
+	mockPay.
+		On("SubscribeCustomer", ctx, customer, "Core", model.BillingIntervalMonth, "eur", 1, (*model.Trial)(nil)).
+		Return(model.ProviderSubscription{ExternalSubscriptionId: "ext_sub_456", Status: model.SubscriptionStatusIncomplete, Currency: "eur"}, nil).Once()
+
+	mockSub.
+		On("CreateSubscription", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Currency == "eur"
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "", 1, nil)
+	assert.NoError(t, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestSubscriberCustomer_CurrencyMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", mock.Anything, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123", Currency: "eur"}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "usd", 1, nil)
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
+
+	mockPay.AssertNotCalled(t, "SubscribeCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockSub.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestSubscriberCustomer_InvalidCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", mock.Anything, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return([]model.Subscription{}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "EUR", 1, nil)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockPay.AssertNotCalled(t, "SubscribeCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSubscriberCustomer_PlanDefaultTrial(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+	trialEnd := time.Now().AddDate(0, 0, 14).UTC()
+
+	mockSub.
+		On("GetCustomer", mock.Anything, "cust_123").
+		Return(customer, nil).Once()
+
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return([]model.Subscription{}, nil).Once()
+
+	// The plan default is resolved to its length.
+	mockPay.
+		On("SubscribeCustomer", ctx, *customer, "Core", model.BillingIntervalMonth, "", 1, &model.Trial{Days: 14, EndBehavior: model.TrialEndBehaviorCancel}).
+		Return(model.ProviderSubscription{ExternalSubscriptionId: "ext_sub_456", Status: model.SubscriptionStatusTrialing, TrialEnd: &trialEnd}, nil).Once()
+
+	mockSub.
+		On("CreateSubscription", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusTrialing && s.TrialEnd != nil && s.TrialEnd.Equal(trialEnd)
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "", 1, &model.Trial{PlanDefault: true, EndBehavior: model.TrialEndBehaviorCancel})
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusTrialing, sub.Status)
 
 	mockSub.AssertExpectations(t)
 	mockPay.AssertExpectations(t)
 }
 
+func TestSubscriberCustomer_InvalidTrial(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	for name, trial := range map[string]*model.Trial{
+		"no default":   {PlanDefault: true},
+		"both":         {Days: 7, PlanDefault: true},
+		"no days":      {EndBehavior: model.TrialEndBehaviorPause},
+		"too long":     {Days: 1000},
+		"end behavior": {Days: 7, EndBehavior: "invoice"},
+	} {
+		plan := "Core"
+		if name == "no default" {
+			plan = "Premium"
+		}
+		_, err := svc.SubscriberCustomer(ctx, "cust_123", plan, model.BillingIntervalMonth, "", 1, trial)
+		assert.IsType(t, model.ValidationErr{}, err, name)
+	}
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
 func TestSubscriberCustomer_CustomerNotFound(t *testing.T) {
 	ctx := context.Background()
 
@@ -188,8 +627,8 @@ func TestSubscriberCustomer_CustomerNotFound(t *testing.T) {
 		On("GetCustomer", mock.Anything, nonExistentCustomerID).
 		Return(nil, nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
-	sub, err := svc.SubscriberCustomer(ctx, nonExistentCustomerID, plan)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriberCustomer(ctx, nonExistentCustomerID, plan, model.BillingIntervalMonth, "", 1, nil)
 	assert.Error(t, err)
 	assert.Equal(t, model.NewCustomerNotFoundErr(nonExistentCustomerID).Error(), err.Error())
 	assert.Empty(t, sub.SubscriptionId)
@@ -215,12 +654,17 @@ func TestSubscriberCustomer_PaymentProviderError(t *testing.T) {
 		On("GetCustomer", mock.Anything, customerId).
 		Return(existingCustomer, nil).Once()
 
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, customerId).
+		Return([]model.Subscription{}, nil).Once()
+
 	mockPay.
-		On("SubscribeCustomer", ctx, *existingCustomer, plan).
-		Return("", expectedErr).Once()
+		On("SubscribeCustomer", ctx, *existingCustomer, plan, model.BillingIntervalMonth, "", 1, (*model.Trial)(nil)).
+		Return(model.ProviderSubscription{}, expectedErr).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
-	sub, err := svc.SubscriberCustomer(ctx, customerId, plan)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriberCustomer(ctx, customerId, plan, model.BillingIntervalMonth, "", 1, nil)
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
 	assert.Empty(t, sub.SubscriptionId)
@@ -248,16 +692,21 @@ func TestSubscriberCustomer_CreateSubscriptionError(t *testing.T) {
 		On("GetCustomer", mock.Anything, customerId).
 		Return(existingCustomer, nil).Once()
 
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, customerId).
+		Return([]model.Subscription{}, nil).Once()
+
 	mockPay.
-		On("SubscribeCustomer", ctx, *existingCustomer, plan).
-		Return(externalSubID, nil).Once()
+		On("SubscribeCustomer", ctx, *existingCustomer, plan, model.BillingIntervalMonth, "", 1, (*model.Trial)(nil)).
+		Return(model.ProviderSubscription{ExternalSubscriptionId: externalSubID, Status: model.SubscriptionStatusIncomplete}, nil).Once()
 
 	mockSub.
 		On("CreateSubscription", ctx, mock.Anything).
 		Return(expectedErr).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
-	sub, err := svc.SubscriberCustomer(ctx, customerId, plan)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriberCustomer(ctx, customerId, plan, model.BillingIntervalMonth, "", 1, nil)
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
 	assert.Empty(t, sub.SubscriptionId)
@@ -275,7 +724,7 @@ func TestSubscriptionStatus_Success(t *testing.T) {
 	customerId := "cust_123"
 	subscriptionId := "sub_abc"
 	externalSubID := "ext_sub_789"
-	status := "active"
+	status := model.SubscriptionStatusActive
 
 	existingCustomer := &model.Customer{
 		CustomerId:         customerId,
@@ -300,7 +749,16 @@ func TestSubscriptionStatus_Success(t *testing.T) {
 		On("GetSubscriptionStatus", ctx, externalSubID).
 		Return(status, nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	// The changed status is stored.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == subscriptionId && s.Status == status && s.LastEventAt == nil && s.SyncedAt != nil
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == "new" && c.Status == status && c.Source == model.StatusChangeSourceReconciliation
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
 	assert.NoError(t, err)
 	assert.Equal(t, status, sub.Status)
@@ -309,24 +767,139 @@ func TestSubscriptionStatus_Success(t *testing.T) {
 	mockPay.AssertExpectations(t)
 }
 
-func TestSubscriptionStatus_CustomerNotFound(t *testing.T) {
+func TestSubscriptionStatus_Unchanged(t *testing.T) {
 	ctx := context.Background()
 
 	mockSub := new(mockSubscription)
 	mockPay := new(mockPaymentProvider)
 
-	customerId := "nonexistent"
+	customerId := "cust_123"
 	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
 
 	mockSub.
 		On("GetCustomer", mock.Anything, customerId).
-		Return(nil, nil).Once()
+		Return(&model.Customer{CustomerId: customerId, ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Status:                 "active",
+		}, nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	mockPay.
+		On("GetSubscriptionStatus", ctx, externalSubID).
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	// No UpdateSubscriptionStatus expectation: an unchanged status is not written.
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
-	assert.Error(t, err)
-	assert.Equal(t, model.NewCustomerNotFoundErr(customerId).Error(), err.Error())
-	assert.Empty(t, sub.SubscriptionId)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusActive, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestSubscriptionStatus_IllegalTransition(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
+
+	mockSub.
+		On("GetCustomer", mock.Anything, customerId).
+		Return(&model.Customer{CustomerId: customerId, ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Status:                 model.SubscriptionStatusCanceled,
+		}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, externalSubID).
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	// No UpdateSubscriptionStatus expectation: the canceled status is kept and still readable.
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusCanceled, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestSubscriptionStatus_UpdateError(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
+	expectedErr := errors.New("update error")
+
+	mockSub.
+		On("GetCustomer", mock.Anything, customerId).
+		Return(&model.Customer{CustomerId: customerId, ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Status:                 "incomplete",
+		}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, externalSubID).
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(expectedErr).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
+	assert.Equal(t, expectedErr, err)
+	assert.Empty(t, sub.SubscriptionId)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestSubscriptionStatus_CustomerNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "nonexistent"
+	subscriptionId := "sub_abc"
+
+	mockSub.
+		On("GetCustomer", mock.Anything, customerId).
+		Return(nil, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
+	assert.Error(t, err)
+	assert.Equal(t, model.NewCustomerNotFoundErr(customerId).Error(), err.Error())
+	assert.Empty(t, sub.SubscriptionId)
 
 	mockSub.AssertExpectations(t)
 }
@@ -353,7 +926,7 @@ func TestSubscriptionStatus_SubscriptionNotFound(t *testing.T) {
 		On("GetSubscription", ctx, customerId, subscriptionId).
 		Return(nil, nil).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
 	assert.Error(t, err)
 	assert.Equal(t, model.NewSubscriptionNotFoundErr(subscriptionId).Error(), err.Error())
@@ -396,7 +969,7 @@ func TestSubscriptionStatus_PaymentProviderError(t *testing.T) {
 		On("GetSubscriptionStatus", ctx, externalSubID).
 		Return("", expectedErr).Once()
 
-	svc := service.NewSubscriptionService(mockSub, mockPay)
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
 	sub, err := svc.SubscriptionStatus(ctx, customerId, subscriptionId)
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
@@ -405,3 +978,1105 @@ func TestSubscriptionStatus_PaymentProviderError(t *testing.T) {
 	mockSub.AssertExpectations(t)
 	mockPay.AssertExpectations(t)
 }
+
+func TestSubscriptionHistory_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	history := []model.StatusChange{
+		{PreviousStatus: model.SubscriptionStatusIncomplete, Status: model.SubscriptionStatusActive, Source: model.StatusChangeSourceWebhook, EventId: "evt_1"},
+		{PreviousStatus: model.SubscriptionStatusActive, Status: model.SubscriptionStatusPastDue, Source: model.StatusChangeSourceWebhook, EventId: "evt_2"},
+	}
+
+	mockSub.
+		On("GetCustomer", ctx, customerId).
+		Return(&model.Customer{CustomerId: customerId}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{SubscriptionId: subscriptionId, CustomerId: customerId}, nil).Once()
+
+	mockSub.
+		On("GetStatusHistory", ctx, customerId, subscriptionId).
+		Return(history, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	res, err := svc.SubscriptionHistory(ctx, customerId, subscriptionId)
+	assert.NoError(t, err)
+	assert.Equal(t, history, res)
+
+	mockSub.AssertExpectations(t)
+}
+
+func TestSubscriptionHistory_SubscriptionNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "nonexistent").
+		Return(nil, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	res, err := svc.SubscriptionHistory(ctx, "cust_123", "nonexistent")
+	assert.Equal(t, model.NewSubscriptionNotFoundErr("nonexistent"), err)
+	assert.Nil(t, res)
+
+	mockSub.AssertExpectations(t)
+}
+
+func TestCancelSubscription_Immediately(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
+	cancellation := model.Cancellation{Mode: model.CancelModeImmediately, Reason: "too expensive"}
+
+	mockSub.
+		On("GetCustomer", ctx, customerId).
+		Return(&model.Customer{CustomerId: customerId}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("CancelSubscription", ctx, externalSubID, cancellation).
+		Return(model.SubscriptionStatusCanceled, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusCanceled && !s.CancelAtPeriodEnd && s.SyncedAt != nil
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == model.SubscriptionStatusActive && c.Status == model.SubscriptionStatusCanceled && c.Source == model.StatusChangeSourceApi
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.CancelSubscription(ctx, customerId, subscriptionId, cancellation)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusCanceled, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestCancelSubscription_AtPeriodEnd(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
+	cancellation := model.Cancellation{Mode: model.CancelModeAtPeriodEnd}
+
+	mockSub.
+		On("GetCustomer", ctx, customerId).
+		Return(&model.Customer{CustomerId: customerId}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("CancelSubscription", ctx, externalSubID, cancellation).
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	// The subscription stays active until the period ends, so no status change is recorded.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusActive && s.CancelAtPeriodEnd
+		}), (*model.StatusChange)(nil)).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.CancelSubscription(ctx, customerId, subscriptionId, cancellation)
+	assert.NoError(t, err)
+	assert.True(t, sub.CancelAtPeriodEnd)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestCancelSubscription_AlreadyCanceled(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: model.SubscriptionStatusCanceled}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.CancelSubscription(ctx, "cust_123", "sub_abc", model.Cancellation{Mode: model.CancelModeImmediately})
+	assert.IsType(t, model.IllegalStatusTransitionErr{}, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertNotCalled(t, "CancelSubscription")
+}
+
+func TestCancelSubscription_UnknownMode(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.CancelSubscription(ctx, "cust_123", "sub_abc", model.Cancellation{Mode: "later"})
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestChangePlan_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customerId := "cust_123"
+	subscriptionId := "sub_abc"
+	externalSubID := "ext_sub_789"
+	change := model.PlanChange{Plan: "Premium", ProrationBehavior: model.ProrationBehaviorAlwaysInvoice}
+
+	mockSub.
+		On("GetCustomer", ctx, customerId).
+		Return(&model.Customer{CustomerId: customerId}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, customerId, subscriptionId).
+		Return(&model.Subscription{
+			SubscriptionId:         subscriptionId,
+			CustomerId:             customerId,
+			ExternalSubscriptionID: externalSubID,
+			Plan:                   "Core",
+			Interval:               model.BillingIntervalYear,
+			Currency:               "usd",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	// The subscription stays on its yearly interval and its currency.
+	mockPay.
+		On("ChangePlan", ctx, externalSubID, model.PlanChange{Plan: "Premium", Interval: model.BillingIntervalYear, Currency: "usd", ProrationBehavior: model.ProrationBehaviorAlwaysInvoice}).
+		Return(nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionPlan", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Plan == "Premium" && s.Interval == model.BillingIntervalYear && s.Status == model.SubscriptionStatusActive
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ChangePlan(ctx, customerId, subscriptionId, change)
+	assert.NoError(t, err)
+	assert.Equal(t, "Premium", sub.Plan)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestChangePlan_SwitchInterval(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	change := model.PlanChange{Plan: "Core", Interval: model.BillingIntervalYear, ProrationBehavior: model.ProrationBehaviorCreateProrations}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123", Currency: "eur"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Plan:                   "Core",
+			Interval:               model.BillingIntervalMonth,
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	// The subscription was stored before its currency was recorded, it is read from the payment provider.
+	mockPay.
+		On("GetSubscriptionCurrency", ctx, "ext_sub_789").
+		Return("eur", nil).Once()
+
+	// The same plan on another interval is a plan change, billed in the currency of the subscription.
+	billed := change
+	billed.Currency = "eur"
+	mockPay.
+		On("ChangePlan", ctx, "ext_sub_789", billed).
+		Return(nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionPlan", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Plan == "Core" && s.Interval == model.BillingIntervalYear
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", change)
+	assert.NoError(t, err)
+	assert.Equal(t, model.BillingIntervalYear, sub.Interval)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestChangePlan_UnknownInterval(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Core", Interval: "week", ProrationBehavior: model.ProrationBehaviorNone})
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestChangePlan_SamePlan(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Plan: "Core", Interval: model.BillingIntervalMonth, Currency: "usd", Status: model.SubscriptionStatusActive}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Core", ProrationBehavior: model.ProrationBehaviorNone})
+	assert.NoError(t, err)
+	assert.Equal(t, "Core", sub.Plan)
+
+	mockSub.AssertNotCalled(t, "UpdateSubscriptionPlan")
+	mockPay.AssertNotCalled(t, "ChangePlan")
+}
+
+func TestChangePlan_SubscriptionEnded(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Plan: "Core", Status: model.SubscriptionStatusCanceled}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Growth", ProrationBehavior: model.ProrationBehaviorNone})
+	assert.IsType(t, model.SubscriptionEndedErr{}, err)
+
+	mockPay.AssertNotCalled(t, "ChangePlan")
+}
+
+func TestChangePlan_UnknownProrationBehavior(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Growth", ProrationBehavior: "sometimes"})
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestPreviewPlanChange_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	change := model.PlanChange{Plan: "Premium", ProrationBehavior: model.ProrationBehaviorCreateProrations}
+	preview := model.InvoicePreview{Currency: "usd", Total: 4500, ProratedAmount: 1500}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Plan:                   "Core",
+			Currency:               "usd",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	billed := change
+	billed.Currency = "usd"
+	mockPay.
+		On("PreviewPlanChange", ctx, "ext_sub_789", billed).
+		Return(preview, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	res, err := svc.PreviewPlanChange(ctx, "cust_123", "sub_abc", change)
+	assert.NoError(t, err)
+	assert.Equal(t, preview, res)
+
+	// The preview changes nothing.
+	mockSub.AssertNotCalled(t, "UpdateSubscriptionPlan")
+	mockPay.AssertNotCalled(t, "ChangePlan")
+	mockPay.AssertExpectations(t)
+}
+
+func TestPauseSubscription_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	resumesAt := time.Now().AddDate(0, 3, 0)
+	pause := model.Pause{ResumesAt: &resumesAt, Behavior: model.PauseBehaviorVoid}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("PauseSubscription", ctx, "ext_sub_789", pause).
+		Return(model.SubscriptionStatusPaused, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusPaused && s.SyncedAt != nil
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == model.SubscriptionStatusActive && c.Status == model.SubscriptionStatusPaused && c.Source == model.StatusChangeSourceApi
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.PauseSubscription(ctx, "cust_123", "sub_abc", pause)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPaused, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestPauseSubscription_StaleWriteReturnsStoredSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	pause := model.Pause{Behavior: model.PauseBehaviorVoid}
+	eventAt := time.Now().Add(-time.Minute)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			CustomerId:             "cust_123",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("PauseSubscription", ctx, "ext_sub_789", pause).
+		Return(model.SubscriptionStatusPaused, nil).Once()
+
+	// The webhook synced the pause in between, the write is stale.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(model.NewStaleSubscriptionUpdateErr("sub_abc")).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			CustomerId:             "cust_123",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPaused,
+			LastEventAt:            &eventAt,
+		}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.PauseSubscription(ctx, "cust_123", "sub_abc", pause)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusPaused, sub.Status)
+	assert.Equal(t, &eventAt, sub.LastEventAt)
+	assert.Nil(t, sub.SyncedAt)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestPauseSubscription_ResumeDateInThePast(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	resumesAt := time.Now().Add(-time.Hour)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.PauseSubscription(ctx, "cust_123", "sub_abc", model.Pause{ResumesAt: &resumesAt, Behavior: model.PauseBehaviorVoid})
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestPauseSubscription_IllegalTransition(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: model.SubscriptionStatusIncomplete}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.PauseSubscription(ctx, "cust_123", "sub_abc", model.Pause{Behavior: model.PauseBehaviorKeepAsDraft})
+	assert.IsType(t, model.IllegalStatusTransitionErr{}, err)
+
+	mockPay.AssertNotCalled(t, "PauseSubscription")
+}
+
+func TestResumeSubscription_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPaused,
+		}, nil).Once()
+
+	mockPay.
+		On("ResumeSubscription", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusActive
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == model.SubscriptionStatusPaused && c.Status == model.SubscriptionStatusActive
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ResumeSubscription(ctx, "cust_123", "sub_abc")
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusActive, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+// TestResumeSubscription_PausedDuringTrial resumes a subscription paused at the end of its trial, the
+// provider reports it trialing again
+func TestResumeSubscription_PausedDuringTrial(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPaused,
+		}, nil).Once()
+
+	mockPay.
+		On("ResumeSubscription", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusTrialing, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusTrialing
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == model.SubscriptionStatusPaused && c.Status == model.SubscriptionStatusTrialing
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ResumeSubscription(ctx, "cust_123", "sub_abc")
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusTrialing, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestResumeSubscription_StoresProviderStatus(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPaused,
+		}, nil).Once()
+
+	mockPay.
+		On("ResumeSubscription", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusIncomplete, nil).Once()
+
+	// The provider already resumed the subscription, its status is stored even if our state machine
+	// wouldn't move there.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusIncomplete && s.SyncedAt != nil
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			return c.PreviousStatus == model.SubscriptionStatusPaused && c.Status == model.SubscriptionStatusIncomplete && c.Source == model.StatusChangeSourceApi
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ResumeSubscription(ctx, "cust_123", "sub_abc")
+	assert.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusIncomplete, sub.Status)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestResumeSubscription_NotPaused(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: model.SubscriptionStatusActive}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.ResumeSubscription(ctx, "cust_123", "sub_abc")
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockPay.AssertNotCalled(t, "ResumeSubscription")
+}
+
+func TestSubscriberCustomer_InvalidQuantity(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", model.BillingIntervalMonth, "", 0, nil)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestSubscriberCustomer_UnknownInterval(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.SubscriberCustomer(ctx, "cust_123", "Core", "week", "", 1, nil)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestChangeQuantity_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	change := model.QuantityChange{Quantity: 25, ProrationBehavior: model.ProrationBehaviorCreateProrations}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Quantity:               10,
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("ChangeQuantity", ctx, "ext_sub_789", change).
+		Return(nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionQuantity", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Quantity == 25
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ChangeQuantity(ctx, "cust_123", "sub_abc", change)
+	assert.NoError(t, err)
+	assert.Equal(t, 25, sub.Quantity)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestChangeQuantity_InvalidQuantity(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.ChangeQuantity(ctx, "cust_123", "sub_abc", model.QuantityChange{Quantity: -1, ProrationBehavior: model.ProrationBehaviorNone})
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockSub.AssertNotCalled(t, "GetCustomer")
+}
+
+func TestAddAddOn_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Currency:               "usd",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("AddAddOn", ctx, "ext_sub_789", "ExtraStorage", "usd", 2).
+		Return("si_123", nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionAddOns", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return len(s.AddOns) == 1 && s.AddOns[0] == model.AddOn{Name: "ExtraStorage", ExternalItemId: "si_123", Quantity: 2}
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.AddAddOn(ctx, "cust_123", "sub_abc", "ExtraStorage", 2)
+	assert.NoError(t, err)
+	assert.Len(t, sub.AddOns, 1)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+// TestAddAddOn_StoreFailed deletes the item created in the payment provider if it can't be stored
+func TestAddAddOn_StoreFailed(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId:         "sub_abc",
+			ExternalSubscriptionID: "ext_sub_789",
+			Currency:               "usd",
+			Status:                 model.SubscriptionStatusActive,
+		}, nil).Once()
+
+	mockPay.
+		On("AddAddOn", ctx, "ext_sub_789", "ExtraStorage", "usd", 2).
+		Return("si_123", nil).Once()
+
+	storeErr := errors.New("dynamo unavailable")
+	mockSub.
+		On("UpdateSubscriptionAddOns", ctx, mock.Anything).
+		Return(storeErr).Once()
+
+	mockPay.
+		On("RemoveAddOn", ctx, "si_123").
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.AddAddOn(ctx, "cust_123", "sub_abc", "ExtraStorage", 2)
+	assert.Equal(t, storeErr, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestAddAddOn_AlreadyAdded(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId: "sub_abc",
+			Status:         model.SubscriptionStatusActive,
+			AddOns:         []model.AddOn{{Name: "ExtraStorage", ExternalItemId: "si_123", Quantity: 1}},
+		}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.AddAddOn(ctx, "cust_123", "sub_abc", "ExtraStorage", 1)
+	assert.IsType(t, model.ValidationErr{}, err)
+
+	mockPay.AssertNotCalled(t, "AddAddOn")
+}
+
+func TestRemoveAddOn_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{
+			SubscriptionId: "sub_abc",
+			Status:         model.SubscriptionStatusActive,
+			AddOns: []model.AddOn{
+				{Name: "ExtraStorage", ExternalItemId: "si_123", Quantity: 1},
+				{Name: "PrioritySupport", ExternalItemId: "si_456", Quantity: 1},
+			},
+		}, nil).Once()
+
+	mockPay.
+		On("RemoveAddOn", ctx, "si_123").
+		Return(nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionAddOns", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return len(s.AddOns) == 1 && s.AddOns[0].Name == "PrioritySupport"
+		})).
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.RemoveAddOn(ctx, "cust_123", "sub_abc", "ExtraStorage")
+	assert.NoError(t, err)
+	assert.Len(t, sub.AddOns, 1)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestRemoveAddOn_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: model.SubscriptionStatusActive}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.RemoveAddOn(ctx, "cust_123", "sub_abc", "ExtraStorage")
+	assert.Equal(t, model.NewAddOnNotFoundErr("sub_abc", "ExtraStorage"), err)
+
+	mockPay.AssertNotCalled(t, "RemoveAddOn")
+}
+
+func TestCreateCheckoutSession_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+	checkout := model.Checkout{Plan: "Core", Interval: model.BillingIntervalYear, SuccessUrl: "https://example.com/success", CancelUrl: "https://example.com/cancel"}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(customer, nil).Once()
+
+	// The customer has no subscription to take a currency from yet.
+	mockSub.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return([]model.Subscription{}, nil).Once()
+
+	mockPay.
+		On("CreateCheckoutSession", ctx, *customer, checkout).
+		Return(model.CheckoutSession{ExternalSessionId: "cs_123", Url: "https://checkout.stripe.com/c/pay/cs_123"}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	session, err := svc.CreateCheckoutSession(ctx, "cust_123", checkout)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://checkout.stripe.com/c/pay/cs_123", session.Url)
+
+	// Nothing is recorded until the checkout is completed
+	mockSub.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestCreateCheckoutSession_CurrencyMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123", Currency: "gbp"}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.CreateCheckoutSession(ctx, "cust_123", model.Checkout{Plan: "Core", Interval: model.BillingIntervalMonth, Currency: "eur"})
+	assert.IsType(t, model.CurrencyMismatchErr{}, err)
+
+	mockPay.AssertNotCalled(t, "CreateCheckoutSession", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateCheckoutSession_CustomerNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_404").
+		Return((*model.Customer)(nil), nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.CreateCheckoutSession(ctx, "cust_404", model.Checkout{Plan: "Core", Interval: model.BillingIntervalMonth})
+	assert.IsType(t, model.CustomerNotFoundErr{}, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertNotCalled(t, "CreateCheckoutSession", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreatePortalSession_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(customer, nil).Once()
+
+	mockPay.
+		On("CreatePortalSession", ctx, *customer).
+		Return("https://billing.stripe.com/p/session/test_123", nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	url, err := svc.CreatePortalSession(ctx, "cust_123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://billing.stripe.com/p/session/test_123", url)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestCreatePortalSession_CustomerNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_404").
+		Return((*model.Customer)(nil), nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.CreatePortalSession(ctx, "cust_404")
+	assert.IsType(t, model.CustomerNotFoundErr{}, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertNotCalled(t, "CreatePortalSession", mock.Anything, mock.Anything)
+}
+
+func TestSetDefaultPaymentMethod_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(customer, nil).Once()
+
+	mockPay.
+		On("PaymentMethods", ctx, *customer).
+		Return([]model.PaymentMethod{
+			{ExternalPaymentMethodId: "pm_1", Default: true},
+			{ExternalPaymentMethodId: "pm_2"},
+		}, nil).Once()
+
+	mockPay.
+		On("SetDefaultPaymentMethod", ctx, *customer, "pm_2").
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	methods, err := svc.SetDefaultPaymentMethod(ctx, "cust_123", "pm_2")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.PaymentMethod{
+		{ExternalPaymentMethodId: "pm_1"},
+		{ExternalPaymentMethodId: "pm_2", Default: true},
+	}, methods)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+func TestDetachPaymentMethod_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(customer, nil).Once()
+
+	mockPay.
+		On("PaymentMethods", ctx, *customer).
+		Return([]model.PaymentMethod{{ExternalPaymentMethodId: "pm_1"}, {ExternalPaymentMethodId: "pm_2"}}, nil).Once()
+
+	mockPay.
+		On("DetachPaymentMethod", ctx, "pm_1").
+		Return(nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	methods, err := svc.DetachPaymentMethod(ctx, "cust_123", "pm_1")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.PaymentMethod{{ExternalPaymentMethodId: "pm_2"}}, methods)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+}
+
+// A payment method of another customer must not be detached
+func TestDetachPaymentMethod_NotOfCustomer(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockPay := new(mockPaymentProvider)
+
+	customer := &model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(customer, nil).Once()
+
+	mockPay.
+		On("PaymentMethods", ctx, *customer).
+		Return([]model.PaymentMethod{{ExternalPaymentMethodId: "pm_1"}}, nil).Once()
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	_, err := svc.DetachPaymentMethod(ctx, "cust_123", "pm_other")
+	assert.IsType(t, model.PaymentMethodNotFoundErr{}, err)
+
+	mockSub.AssertExpectations(t)
+	mockPay.AssertExpectations(t)
+	mockPay.AssertNotCalled(t, "DetachPaymentMethod", mock.Anything, mock.Anything)
+}
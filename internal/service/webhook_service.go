@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/port"
+	"github.com/DenisBarabanshchikov/subscription/pkg/uuid"
+	"github.com/pkg/errors"
+	"log"
+	"time"
+)
+
+type WebhookService interface {
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+	ListEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error)
+	GetEvent(ctx context.Context, eventId string) (model.Event, error)
+	ReplayEvent(ctx context.Context, eventId string) (model.Event, error)
+	ReplayEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error)
+	RetryDueEvents(ctx context.Context) error
+}
+
+// RetryConfig controls the background retries of failed events. The delay before a retry doubles
+// with every attempt, starting at BaseDelay and capped at MaxDelay. After MaxAttempts the event is
+// left for manual review.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int
+}
+
+// retryClaimTimeout postpones a claimed retry, so it is picked up again if the instance running it dies
+const retryClaimTimeout = 5 * time.Minute
+
+type eventHandler func(ctx context.Context, event model.Event) error
+
+// eventIgnoredErr is returned by an event handler when the event is acknowledged without any change
+type eventIgnoredErr struct {
+	reason string
+}
+
+func (e eventIgnoredErr) Error() string {
+	return e.reason
+}
+
+type webhookService struct {
+	customer        port.Subscription
+	events          port.Event
+	paymentProvider port.PaymentProvider
+	retry           RetryConfig
+	handlers        map[string]eventHandler
+}
+
+func NewWebhookService(customer port.Subscription, events port.Event, paymentProvider port.PaymentProvider, retry RetryConfig) WebhookService {
+	s := &webhookService{
+		customer:        customer,
+		events:          events,
+		paymentProvider: paymentProvider,
+		retry:           retry,
+	}
+	s.handlers = map[string]eventHandler{
+		model.EventSubscriptionCreated:      s.handleSubscriptionChanged,
+		model.EventSubscriptionUpdated:      s.handleSubscriptionChanged,
+		model.EventSubscriptionDeleted:      s.handleSubscriptionChanged,
+		model.EventSubscriptionPaused:       s.handleSubscriptionChanged,
+		model.EventSubscriptionResumed:      s.handleSubscriptionChanged,
+		model.EventInvoicePaid:              s.handleInvoice,
+		model.EventInvoicePaymentFailed:     s.handleInvoice,
+		model.EventCustomerDeleted:          s.handleCustomerDeleted,
+		model.EventCheckoutSessionCompleted: s.handleCheckoutSessionCompleted,
+	}
+	return s
+}
+
+// HandleWebhook records the outcome of a failed event instead of returning it, the event is then retried
+// in the background and the provider gets its answer without waiting for the retries. A verified event
+// which can't be read is recorded as failed as well.
+func (s webhookService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, readErr := s.paymentProvider.ConstructEvent(ctx, payload, signature)
+	var unreadableErr model.UnreadableEventErr
+	if readErr != nil && !errors.As(readErr, &unreadableErr) {
+		return readErr
+	}
+
+	event.Status = model.EventStatusProcessing
+	event.Attempts = 1
+	err := s.events.CreateEvent(ctx, event)
+	if err != nil {
+		var alreadyExistsErr model.EventAlreadyExistsErr
+		if !errors.As(err, &alreadyExistsErr) {
+			return err
+		}
+
+		stored, err := s.events.GetEvent(ctx, event.EventId)
+		if err != nil {
+			return err
+		}
+		if stored != nil && stored.Status.IsDone() {
+			log.Printf("skipping duplicate webhook event '%s', already %s", event.EventId, stored.Status)
+			return nil
+		}
+		if stored != nil {
+			event.Attempts = stored.Attempts + 1
+		}
+	}
+
+	if readErr != nil {
+		_, err = s.recordUnreadable(ctx, event, readErr)
+		return err
+	}
+	_, err = s.process(ctx, event)
+
+	return err
+}
+
+func (s webhookService) ListEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error) {
+	if filter.From.After(filter.To) {
+		return nil, model.NewValidationErr("'from' must not be after 'to'")
+	}
+	return s.events.GetEvents(ctx, filter)
+}
+
+func (s webhookService) GetEvent(ctx context.Context, eventId string) (model.Event, error) {
+	event, err := s.events.GetEvent(ctx, eventId)
+	if err != nil {
+		return model.Event{}, err
+	}
+	if event == nil {
+		return model.Event{}, model.NewEventNotFoundErr(eventId)
+	}
+
+	return *event, nil
+}
+
+// ReplayEvent processes a stored event again, regardless of its previous outcome
+func (s webhookService) ReplayEvent(ctx context.Context, eventId string) (model.Event, error) {
+	stored, err := s.GetEvent(ctx, eventId)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	return s.replay(ctx, stored)
+}
+
+func (s webhookService) ReplayEvents(ctx context.Context, filter model.EventFilter) ([]model.Event, error) {
+	events, err := s.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.Event, 0, len(events))
+	for _, stored := range events {
+		event, err := s.replay(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, event)
+	}
+
+	return res, nil
+}
+
+// RetryDueEvents replays the failed events whose retry is due. Every event is claimed first, so
+// instances running at the same time don't retry the same event twice.
+func (s webhookService) RetryDueEvents(ctx context.Context) error {
+	now := time.Now()
+	events, err := s.events.GetDueEvents(ctx, now, s.retry.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, stored := range events {
+		claimed, err := s.events.ClaimEvent(ctx, stored, now.Add(retryClaimTimeout))
+		if err != nil {
+			log.Printf("failed to claim webhook event '%s' for retry: %v", stored.EventId, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err = s.replay(ctx, stored); err != nil {
+			log.Printf("failed to retry webhook event '%s': %v", stored.EventId, err)
+		}
+	}
+
+	return nil
+}
+
+// replay returns an error only if the outcome could not be recorded, handler failures are part of the event
+func (s webhookService) replay(ctx context.Context, stored model.Event) (model.Event, error) {
+	event, err := s.paymentProvider.ParseEvent(ctx, stored.Payload)
+	var unreadableErr model.UnreadableEventErr
+	if err != nil && !errors.As(err, &unreadableErr) {
+		return model.Event{}, err
+	}
+	event.Attempts = stored.Attempts + 1
+
+	log.Printf("replaying webhook event '%s', attempt %d", event.EventId, event.Attempts)
+
+	if err != nil {
+		return s.recordUnreadable(ctx, event, err)
+	}
+	return s.process(ctx, event)
+}
+
+// recordUnreadable records an event which can't be read as failed. It isn't retried, reading it again
+// fails the same way until the mapping is fixed and the event is replayed.
+func (s webhookService) recordUnreadable(ctx context.Context, event model.Event, readErr error) (model.Event, error) {
+	log.Printf("webhook event '%s' could not be read: %v", event.EventId, readErr)
+	event.Status = model.EventStatusFailed
+	event.Error = readErr.Error()
+	event.NextAttemptAt = nil
+	if err := s.events.UpdateEvent(ctx, event); err != nil {
+		return model.Event{}, err
+	}
+
+	return event, nil
+}
+
+// process runs the handler of the event and records the outcome. A failed event is scheduled for a
+// retry, or left for manual review once it has used up its attempts. The returned error is the error
+// of recording the outcome, handler failures are part of the event.
+func (s webhookService) process(ctx context.Context, event model.Event) (model.Event, error) {
+	handleErr := s.dispatch(ctx, event)
+
+	var ignoredErr eventIgnoredErr
+	var transitionErr model.IllegalStatusTransitionErr
+	var invalidErr model.InvalidWebhookErr
+	var customerErr model.CustomerNotFoundErr
+	event.NextAttemptAt = nil
+	switch {
+	case handleErr == nil:
+		event.Status = model.EventStatusProcessed
+		event.Error = ""
+	case errors.As(handleErr, &ignoredErr):
+		log.Printf("ignoring webhook event '%s': %s", event.EventId, ignoredErr.reason)
+		event.Status = model.EventStatusIgnored
+		event.Error = ignoredErr.reason
+	case errors.As(handleErr, &customerErr):
+		// Customers are only created by this service, events of other customers are not ours
+		log.Printf("ignoring webhook event '%s': %v", event.EventId, customerErr)
+		event.Status = model.EventStatusIgnored
+		event.Error = customerErr.Error()
+	case errors.As(handleErr, &transitionErr), errors.As(handleErr, &invalidErr):
+		// Retrying can't fix a status our state machine doesn't allow, nor an event without its data
+		log.Printf("webhook event '%s' needs manual review: %v", event.EventId, handleErr)
+		event.Status = model.EventStatusManualReview
+		event.Error = handleErr.Error()
+	case event.Attempts >= s.retry.MaxAttempts:
+		log.Printf("webhook event '%s' failed after %d attempts, needs manual review: %v", event.EventId, event.Attempts, handleErr)
+		event.Status = model.EventStatusManualReview
+		event.Error = handleErr.Error()
+	default:
+		nextAttemptAt := time.Now().Add(s.retryDelay(event.Attempts))
+		log.Printf("webhook event '%s' failed, retrying at %s: %v", event.EventId, nextAttemptAt.Format(time.RFC3339), handleErr)
+		event.Status = model.EventStatusFailed
+		event.Error = handleErr.Error()
+		event.NextAttemptAt = &nextAttemptAt
+	}
+
+	if err := s.events.UpdateEvent(ctx, event); err != nil {
+		return model.Event{}, err
+	}
+
+	return event, nil
+}
+
+// retryDelay doubles the base delay with every attempt already made
+func (s webhookService) retryDelay(attempts int) time.Duration {
+	delay := s.retry.BaseDelay
+	for i := 1; i < attempts && delay < s.retry.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.retry.MaxDelay)
+}
+
+func (s webhookService) dispatch(ctx context.Context, event model.Event) error {
+	handler, ok := s.handlers[event.Type]
+	if !ok {
+		return eventIgnoredErr{reason: fmt.Sprintf("unsupported event type '%s'", event.Type)}
+	}
+	return handler(ctx, event)
+}
+
+func (s webhookService) handleSubscriptionChanged(ctx context.Context, event model.Event) error {
+	if event.Subscription == nil {
+		return model.NewInvalidWebhookErr(fmt.Sprintf("event '%s' has no subscription", event.EventId))
+	}
+	return s.updateStatus(ctx, event, event.Subscription.ExternalSubscriptionId, event.Subscription.Status, &event.Subscription.CancelAtPeriodEnd)
+}
+
+// Invoice events carry no subscription status, so the current one is fetched from the provider
+func (s webhookService) handleInvoice(ctx context.Context, event model.Event) error {
+	if event.Invoice == nil || event.Invoice.ExternalSubscriptionId == "" {
+		return eventIgnoredErr{reason: "invoice is not related to a subscription"}
+	}
+
+	status, err := s.paymentProvider.GetSubscriptionStatus(ctx, event.Invoice.ExternalSubscriptionId)
+	if err != nil {
+		return err
+	}
+
+	return s.updateStatus(ctx, event, event.Invoice.ExternalSubscriptionId, status, nil)
+}
+
+// Deleting a customer in the provider cancels all of its subscriptions
+func (s webhookService) handleCustomerDeleted(ctx context.Context, event model.Event) error {
+	if event.Customer == nil {
+		return model.NewInvalidWebhookErr(fmt.Sprintf("event '%s' has no customer", event.EventId))
+	}
+
+	customer, err := s.customer.GetCustomerByExternalId(ctx, event.Customer.ExternalCustomerId)
+	if err != nil {
+		return err
+	}
+	if customer == nil {
+		return model.NewCustomerNotFoundErr(event.Customer.ExternalCustomerId)
+	}
+
+	subscriptions, err := s.customer.GetSubscriptions(ctx, customer.CustomerId)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		// Already canceled or expired subscriptions are left as they are
+		if subscription.Status == model.SubscriptionStatusCanceled || !subscription.Status.CanTransitionTo(model.SubscriptionStatusCanceled) {
+			continue
+		}
+		change := newStatusChange(subscription.Status, model.SubscriptionStatusCanceled, model.StatusChangeSourceWebhook, event.EventId)
+		subscription.Status = model.SubscriptionStatusCanceled
+		subscription.LastEventAt = &event.CreatedAt
+		err = s.customer.UpdateSubscriptionStatus(ctx, subscription, change)
+		var staleErr model.StaleSubscriptionUpdateErr
+		if err != nil && !errors.As(err, &staleErr) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A completed checkout records the subscription the customer just paid for. The session doesn't
+// carry the subscription status, so the current one is fetched from the provider.
+func (s webhookService) handleCheckoutSessionCompleted(ctx context.Context, event model.Event) error {
+	session := event.CheckoutSession
+	if session == nil {
+		return model.NewInvalidWebhookErr(fmt.Sprintf("event '%s' has no checkout session", event.EventId))
+	}
+	if session.ExternalSubscriptionId == "" {
+		return eventIgnoredErr{reason: "checkout session did not create a subscription"}
+	}
+	if session.Plan == "" {
+		return eventIgnoredErr{reason: "checkout session has no plan, it was not created by this service"}
+	}
+
+	existing, err := s.customer.GetSubscriptionByExternalId(ctx, session.ExternalSubscriptionId)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return eventIgnoredErr{reason: fmt.Sprintf("subscription '%s' is already recorded", existing.SubscriptionId)}
+	}
+
+	customer, err := s.customer.GetCustomerByExternalId(ctx, session.ExternalCustomerId)
+	if err != nil {
+		return err
+	}
+	if customer == nil {
+		return model.NewCustomerNotFoundErr(session.ExternalCustomerId)
+	}
+
+	status, err := s.paymentProvider.GetSubscriptionStatus(ctx, session.ExternalSubscriptionId)
+	if err != nil {
+		return err
+	}
+	interval := session.Interval
+	if interval == "" {
+		// Sessions created before billing intervals were introduced are monthly
+		interval = model.BillingIntervalMonth
+	}
+
+	err = s.customer.CreateSubscription(ctx, model.Subscription{
+		SubscriptionId:         uuid.GenerateUUID(),
+		CustomerId:             customer.CustomerId,
+		ExternalSubscriptionID: session.ExternalSubscriptionId,
+		Plan:                   session.Plan,
+		Interval:               interval,
+		Currency:               session.Currency,
+		Quantity:               1,
+		Status:                 status,
+		LastEventAt:            &event.CreatedAt,
+	})
+	var existsErr model.SubscriptionAlreadyExistsErr
+	if errors.As(err, &existsErr) {
+		// A redelivery of the event was handled at the same time
+		return eventIgnoredErr{reason: existsErr.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	// The first subscription of the customer sets their currency
+	if customer.Currency == "" && session.Currency != "" {
+		return s.customer.SetCustomerCurrency(ctx, customer.CustomerId, session.Currency)
+	}
+	return nil
+}
+
+// updateStatus never lets an event older than the last synced one overwrite the status. The
+// cancellation is only synced if the event carries it.
+func (s webhookService) updateStatus(ctx context.Context, event model.Event, externalSubscriptionId string, status model.SubscriptionStatus, cancelAtPeriodEnd *bool) error {
+	eventAt := event.CreatedAt
+	subscription, err := s.customer.GetSubscriptionByExternalId(ctx, externalSubscriptionId)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return model.NewSubscriptionNotFoundErr(externalSubscriptionId)
+	}
+	if subscription.LastEventAt != nil && subscription.LastEventAt.After(eventAt) {
+		return eventIgnoredErr{reason: model.NewStaleSubscriptionUpdateErr(subscription.SubscriptionId).Error()}
+	}
+	if subscription.LastEventAt != nil && subscription.LastEventAt.Equal(eventAt) {
+		// Event times are in whole seconds, an event of the same second as the last synced one may be
+		// older than it. The current status is read from the provider instead, the cancellation is left.
+		status, err = s.paymentProvider.GetSubscriptionStatus(ctx, externalSubscriptionId)
+		if err != nil {
+			return err
+		}
+		cancelAtPeriodEnd = nil
+	}
+	if !subscription.Status.CanTransitionTo(status) {
+		return model.NewIllegalStatusTransitionErr(subscription.SubscriptionId, subscription.Status, status)
+	}
+
+	// The write is done even for an unchanged status to move LastEventAt forward
+	change := newStatusChange(subscription.Status, status, model.StatusChangeSourceWebhook, event.EventId)
+	subscription.Status = status
+	subscription.LastEventAt = &eventAt
+	if cancelAtPeriodEnd != nil {
+		subscription.CancelAtPeriodEnd = *cancelAtPeriodEnd
+	}
+
+	err = s.customer.UpdateSubscriptionStatus(ctx, *subscription, change)
+	var staleErr model.StaleSubscriptionUpdateErr
+	if errors.As(err, &staleErr) {
+		return eventIgnoredErr{reason: staleErr.Error()}
+	}
+
+	return err
+}
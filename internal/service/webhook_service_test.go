@@ -0,0 +1,1296 @@
+//go:build unit
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DenisBarabanshchikov/subscription/internal/model"
+	"github.com/DenisBarabanshchikov/subscription/internal/service"
+)
+
+var (
+	webhookPayload   = []byte(`{"id":"evt_123"}`)
+	webhookSignature = "t=1,v1=abc"
+	retryConfig      = service.RetryConfig{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, BatchSize: 10}
+)
+
+// expectEventRecorded expects a first delivery to be stored and its outcome to be recorded.
+func expectEventRecorded(ctx context.Context, mockEvt *mockEvent, status model.EventStatus) {
+	mockEvt.
+		On("CreateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.EventId == "evt_123" && e.Status == model.EventStatusProcessing && e.Attempts == 1
+		})).
+		Return(nil).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.EventId == "evt_123" && e.Status == status && e.Attempts == 1
+		})).
+		Return(nil).Once()
+}
+
+func TestHandleWebhook_InvalidSignature(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	expectedErr := model.NewInvalidWebhookErr("webhook had no valid signature")
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, "forged").
+		Return(model.Event{}, expectedErr).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, "forged")
+	assert.Equal(t, expectedErr, err)
+
+	mockPay.AssertExpectations(t)
+}
+
+func TestHandleWebhook_UnknownEventType(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(model.Event{EventId: "evt_123", Type: "charge.refunded"}, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_SubscriptionUpdated(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventSubscriptionUpdated,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "active",
+		},
+	}
+	existingSubscription := &model.Subscription{
+		SubscriptionId:         "sub_abc",
+		CustomerId:             "cust_123",
+		ExternalSubscriptionID: "ext_sub_789",
+		Status:                 "incomplete",
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(existingSubscription, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" && s.Status == "active"
+		}), mock.MatchedBy(func(c *model.StatusChange) bool {
+			// The change is recorded in the status history.
+			return c.PreviousStatus == "incomplete" && c.Status == "active" &&
+				c.Source == model.StatusChangeSourceWebhook && c.EventId == "evt_123"
+		})).
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_SubscriptionUnchanged(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	syncedAt := time.Unix(1700000000, 0).UTC()
+	eventAt := syncedAt.Add(time.Minute)
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: eventAt,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "active",
+			CancelAtPeriodEnd:      true,
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active", LastEventAt: &syncedAt}, nil).Once()
+
+	// The status is the same, but LastEventAt and the cancellation are still synced.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == "active" && s.LastEventAt.Equal(eventAt) && s.CancelAtPeriodEnd
+		}), (*model.StatusChange)(nil)).
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_OutOfOrderEvent(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	syncedAt := time.Unix(1700000000, 0).UTC()
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: syncedAt.Add(-time.Minute),
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "incomplete",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	// The stored subscription was synced from a newer event, so no update is expected.
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active", LastEventAt: &syncedAt}, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_SameSecondEvent(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	syncedAt := time.Unix(1700000000, 0).UTC()
+	// Created in the same second as the event the subscription was activated by
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionCreated,
+		CreatedAt: syncedAt,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "incomplete",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active", LastEventAt: &syncedAt}, nil).Once()
+
+	// The payload can't be told from a newer one, the provider has the current status
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.Status == model.SubscriptionStatusActive
+		}), (*model.StatusChange)(nil)).
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_StaleConditionalWrite(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPastDue,
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active"}, nil).Once()
+
+	// A newer event was written concurrently and the conditional write is rejected.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(model.NewStaleSubscriptionUpdateErr("sub_abc")).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+// expectPlanChangeRace expects a status sync of the webhook and a plan change of the API on the same
+// subscription, each writing only its own attributes. The order of the writes is returned in writes.
+func expectPlanChangeRace(ctx context.Context, mockSub *mockSubscription, mockPay *mockPaymentProvider, writes *[]string) {
+	mockPay.
+		On("ChangePlan", ctx, "ext_sub_789", model.PlanChange{Plan: "Premium", Interval: model.BillingIntervalMonth, Currency: "usd", ProrationBehavior: model.ProrationBehaviorAlwaysInvoice}).
+		Return(nil).Once()
+
+	mockSub.
+		On("GetCustomer", ctx, "cust_123").
+		Return(&model.Customer{CustomerId: "cust_123"}, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionPlan", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" && s.Plan == "Premium"
+		})).
+		Run(func(mock.Arguments) { *writes = append(*writes, "plan") }).
+		Return(nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" && s.Status == model.SubscriptionStatusPastDue
+		}), mock.Anything).
+		Run(func(mock.Arguments) { *writes = append(*writes, "status") }).
+		Return(nil).Once()
+}
+
+// TestHandleWebhook_PlanChangedBeforeStatusWrite changes the plan after the webhook read the subscription,
+// the status sync written last doesn't carry the plan it read.
+func TestHandleWebhook_PlanChangedBeforeStatusWrite(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPastDue,
+		},
+	}
+	stored := model.Subscription{
+		SubscriptionId:         "sub_abc",
+		CustomerId:             "cust_123",
+		ExternalSubscriptionID: "ext_sub_789",
+		Plan:                   "Core",
+		Interval:               model.BillingIntervalMonth,
+		Currency:               "usd",
+		Status:                 model.SubscriptionStatusActive,
+	}
+	subscriptionSvc := service.NewSubscriptionService(mockSub, mockPay, plans)
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	webhookRead := stored
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Run(func(mock.Arguments) {
+			_, err := subscriptionSvc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Premium", ProrationBehavior: model.ProrationBehaviorAlwaysInvoice})
+			assert.NoError(t, err)
+		}).
+		Return(&webhookRead, nil).Once()
+
+	apiRead := stored
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Return(&apiRead, nil).Once()
+
+	var writes []string
+	expectPlanChangeRace(ctx, mockSub, mockPay, &writes)
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"plan", "status"}, writes)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+// TestHandleWebhook_StatusWrittenBeforePlanChange syncs the status after the API read the subscription,
+// the plan change written last doesn't carry the status it read.
+func TestHandleWebhook_StatusWrittenBeforePlanChange(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 model.SubscriptionStatusPastDue,
+		},
+	}
+	stored := model.Subscription{
+		SubscriptionId:         "sub_abc",
+		CustomerId:             "cust_123",
+		ExternalSubscriptionID: "ext_sub_789",
+		Plan:                   "Core",
+		Interval:               model.BillingIntervalMonth,
+		Currency:               "usd",
+		Status:                 model.SubscriptionStatusActive,
+	}
+	webhookSvc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	webhookRead := stored
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&webhookRead, nil).Once()
+
+	apiRead := stored
+	mockSub.
+		On("GetSubscription", ctx, "cust_123", "sub_abc").
+		Run(func(mock.Arguments) {
+			assert.NoError(t, webhookSvc.HandleWebhook(ctx, webhookPayload, webhookSignature))
+		}).
+		Return(&apiRead, nil).Once()
+
+	var writes []string
+	expectPlanChangeRace(ctx, mockSub, mockPay, &writes)
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewSubscriptionService(mockSub, mockPay, plans)
+	sub, err := svc.ChangePlan(ctx, "cust_123", "sub_abc", model.PlanChange{Plan: "Premium", ProrationBehavior: model.ProrationBehaviorAlwaysInvoice})
+	assert.NoError(t, err)
+	assert.Equal(t, "Premium", sub.Plan)
+	assert.Equal(t, []string{"status", "plan"}, writes)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_IllegalTransitionNeedsManualReview(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventSubscriptionUpdated,
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 model.SubscriptionStatusActive,
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	// A canceled subscription never becomes active again, so nothing is written.
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: model.SubscriptionStatusCanceled}, nil).Once()
+
+	// Retrying doesn't help, the event goes straight to manual review.
+	expectEventRecorded(ctx, mockEvt, model.EventStatusManualReview)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_MalformedEventNeedsManualReview(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	// The subscription event carries no subscription.
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(model.Event{EventId: "evt_123", Type: model.EventSubscriptionUpdated}, nil).Once()
+
+	// Retrying reads the same event again, it goes straight to manual review.
+	expectEventRecorded(ctx, mockEvt, model.EventStatusManualReview)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_UnknownCustomerIsIgnored(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:  "evt_123",
+		Type:     model.EventCustomerDeleted,
+		Customer: &model.EventCustomer{ExternalCustomerId: "ext_cus_unknown"},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	// The customer was not created by this service.
+	mockSub.
+		On("GetCustomerByExternalId", ctx, "ext_cus_unknown").
+		Return(nil, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_SubscriptionNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventSubscriptionDeleted,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_unknown",
+			Status:                 "canceled",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_unknown").
+		Return(nil, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusFailed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	// The failure is recorded for a retry, the provider gets a success
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_InvoicePaymentFailed(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventInvoicePaymentFailed,
+		Invoice: &model.EventInvoice{
+			ExternalInvoiceId:      "in_123",
+			ExternalSubscriptionId: "ext_sub_789",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusPastDue, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active"}, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == "sub_abc" && s.Status == "past_due"
+		}), mock.Anything).
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_InvoiceWithoutSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventInvoicePaid,
+		Invoice: &model.EventInvoice{ExternalInvoiceId: "in_123"},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_CheckoutSessionCompleted(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	createdAt := time.Now().UTC()
+	event := model.Event{
+		EventId:   "evt_123",
+		Type:      model.EventCheckoutSessionCompleted,
+		CreatedAt: createdAt,
+		CheckoutSession: &model.EventCheckoutSession{
+			ExternalSessionId:      "cs_123",
+			ExternalCustomerId:     "ext_cus_123",
+			ExternalSubscriptionId: "ext_sub_789",
+			Plan:                   "Growth",
+			Interval:               model.BillingIntervalYear,
+			Currency:               "eur",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return((*model.Subscription)(nil), nil).Once()
+
+	mockSub.
+		On("GetCustomerByExternalId", ctx, "ext_cus_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	mockSub.
+		On("CreateSubscription", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId != "" &&
+				s.CustomerId == "cust_123" &&
+				s.ExternalSubscriptionID == "ext_sub_789" &&
+				s.Plan == "Growth" &&
+				s.Interval == model.BillingIntervalYear &&
+				s.Currency == "eur" &&
+				s.Quantity == 1 &&
+				s.Status == model.SubscriptionStatusActive &&
+				s.LastEventAt.Equal(createdAt)
+		})).
+		Return(nil).Once()
+
+	// The completed checkout is the first subscription of the customer
+	mockSub.
+		On("SetCustomerCurrency", ctx, "cust_123", "eur").
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_CheckoutSessionAlreadyRecorded(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventCheckoutSessionCompleted,
+		CheckoutSession: &model.EventCheckoutSession{
+			ExternalCustomerId:     "ext_cus_123",
+			ExternalSubscriptionId: "ext_sub_789",
+			Plan:                   "Growth",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "active"}, nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockSub.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_CheckoutSessionRecordedConcurrently(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventCheckoutSessionCompleted,
+		CheckoutSession: &model.EventCheckoutSession{
+			ExternalCustomerId:     "ext_cus_123",
+			ExternalSubscriptionId: "ext_sub_789",
+			Plan:                   "Growth",
+			Currency:               "eur",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return((*model.Subscription)(nil), nil).Once()
+
+	mockSub.
+		On("GetCustomerByExternalId", ctx, "ext_cus_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return(model.SubscriptionStatusActive, nil).Once()
+
+	// A redelivery of the event created the subscription after the lookup above.
+	mockSub.
+		On("CreateSubscription", ctx, mock.Anything).
+		Return(model.NewSubscriptionAlreadyExistsErr("ext_sub_789")).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusIgnored)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockSub.AssertNotCalled(t, "SetCustomerCurrency", mock.Anything, mock.Anything, mock.Anything)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_CustomerDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId:  "evt_123",
+		Type:     model.EventCustomerDeleted,
+		Customer: &model.EventCustomer{ExternalCustomerId: "ext_cus_123"},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockSub.
+		On("GetCustomerByExternalId", ctx, "ext_cus_123").
+		Return(&model.Customer{CustomerId: "cust_123", ExternalCustomerId: "ext_cus_123"}, nil).Once()
+
+	mockSub.
+		On("GetSubscriptions", ctx, "cust_123").
+		Return([]model.Subscription{
+			{SubscriptionId: "sub_active", CustomerId: "cust_123", Status: "active"},
+			{SubscriptionId: "sub_canceled", CustomerId: "cust_123", Status: "canceled"},
+		}, nil).Once()
+
+	// Only the subscription which is not canceled yet is updated.
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.MatchedBy(func(s model.Subscription) bool {
+			return s.SubscriptionId == "sub_active" && s.Status == "canceled"
+		}), mock.Anything).
+		Return(nil).Once()
+
+	expectEventRecorded(ctx, mockEvt, model.EventStatusProcessed)
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_DuplicateProcessedEvent(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(model.Event{EventId: "evt_123", Type: model.EventSubscriptionUpdated}, nil).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(model.NewEventAlreadyExistsErr("evt_123")).Once()
+
+	// The event was already processed, so no handler and no update are expected.
+	mockEvt.
+		On("GetEvent", ctx, "evt_123").
+		Return(&model.Event{EventId: "evt_123", Status: model.EventStatusProcessed, Attempts: 1}, nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_RedeliveredFailedEvent(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventSubscriptionUpdated,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "active",
+		},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(model.NewEventAlreadyExistsErr("evt_123")).Once()
+
+	mockEvt.
+		On("GetEvent", ctx, "evt_123").
+		Return(&model.Event{EventId: "evt_123", Status: model.EventStatusFailed, Attempts: 2}, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "incomplete"}, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(nil).Once()
+
+	// The retry is counted as the third attempt.
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusProcessed && e.Attempts == 3 && e.Error == ""
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_UnreadableEventIsRecorded(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	readErr := model.NewUnreadableEventErr("evt_123", "unknown subscription status 'unknown_status'")
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(model.Event{EventId: "evt_123", Type: model.EventSubscriptionUpdated, Payload: webhookPayload}, readErr).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.EventId == "evt_123" && e.Status == model.EventStatusProcessing && e.Attempts == 1
+		})).
+		Return(nil).Once()
+
+	// Failed without a retry, reading it again fails the same way
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.EventId == "evt_123" && e.Status == model.EventStatusFailed && e.Error == readErr.Error() && e.NextAttemptAt == nil
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_FailureIsRecorded(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventInvoicePaid,
+		Invoice: &model.EventInvoice{ExternalSubscriptionId: "ext_sub_789"},
+	}
+	expectedErr := errors.New("payment provider error")
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return("", expectedErr).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(nil).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusFailed && e.Error == "payment provider error" && e.Attempts == 1
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_FailureIsScheduledForRetry(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventInvoicePaid,
+		Invoice: &model.EventInvoice{ExternalSubscriptionId: "ext_sub_789"},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return("", errors.New("payment provider error")).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(model.NewEventAlreadyExistsErr("evt_123")).Once()
+
+	mockEvt.
+		On("GetEvent", ctx, "evt_123").
+		Return(&model.Event{EventId: "evt_123", Status: model.EventStatusFailed, Attempts: 1}, nil).Once()
+
+	// The second attempt waits twice the base delay
+	before := time.Now()
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusFailed && e.Attempts == 2 && e.NextAttemptAt != nil &&
+				!e.NextAttemptAt.Before(before.Add(2*time.Minute)) && e.NextAttemptAt.Before(time.Now().Add(2*time.Minute+time.Second))
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestHandleWebhook_LastFailureNeedsManualReview(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	event := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventInvoicePaid,
+		Invoice: &model.EventInvoice{ExternalSubscriptionId: "ext_sub_789"},
+	}
+
+	mockPay.
+		On("ConstructEvent", ctx, webhookPayload, webhookSignature).
+		Return(event, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return("", errors.New("payment provider error")).Once()
+
+	mockEvt.
+		On("CreateEvent", ctx, mock.Anything).
+		Return(model.NewEventAlreadyExistsErr("evt_123")).Once()
+
+	mockEvt.
+		On("GetEvent", ctx, "evt_123").
+		Return(&model.Event{EventId: "evt_123", Status: model.EventStatusFailed, Attempts: 2}, nil).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusManualReview && e.Attempts == 3 && e.NextAttemptAt == nil
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.HandleWebhook(ctx, webhookPayload, webhookSignature)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestRetryDueEvents_SkipsEventsClaimedElsewhere(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	nextAttemptAt := time.Unix(1700000000, 0).UTC()
+	claimed := model.Event{EventId: "evt_123", Payload: webhookPayload, Status: model.EventStatusFailed, Attempts: 1, NextAttemptAt: &nextAttemptAt}
+	taken := model.Event{EventId: "evt_456", Payload: []byte(`{"id":"evt_456"}`), Status: model.EventStatusFailed, Attempts: 1, NextAttemptAt: &nextAttemptAt}
+
+	mockEvt.
+		On("GetDueEvents", ctx, mock.AnythingOfType("time.Time"), 10).
+		Return([]model.Event{claimed, taken}, nil).Once()
+
+	mockEvt.
+		On("ClaimEvent", ctx, claimed, mock.AnythingOfType("time.Time")).
+		Return(true, nil).Once()
+
+	mockEvt.
+		On("ClaimEvent", ctx, taken, mock.AnythingOfType("time.Time")).
+		Return(false, nil).Once()
+
+	mockPay.
+		On("ParseEvent", ctx, webhookPayload).
+		Return(model.Event{
+			EventId:      "evt_123",
+			Type:         model.EventSubscriptionDeleted,
+			Subscription: &model.EventSubscription{ExternalSubscriptionId: "ext_sub_789", Status: "canceled"},
+		}, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_789", Status: "active"}, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(nil).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.EventId == "evt_123" && e.Status == model.EventStatusProcessed && e.Attempts == 2 && e.NextAttemptAt == nil
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	err := svc.RetryDueEvents(ctx)
+	assert.NoError(t, err)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestListEvents_InvalidRange(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	now := time.Now()
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	events, err := svc.ListEvents(ctx, model.EventFilter{From: now, To: now.Add(-time.Hour)})
+	assert.IsType(t, model.ValidationErr{}, err)
+	assert.Nil(t, events)
+
+	mockEvt.AssertExpectations(t)
+}
+
+func TestGetEvent_NotFound(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	mockEvt.
+		On("GetEvent", ctx, "evt_unknown").
+		Return(nil, nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	_, err := svc.GetEvent(ctx, "evt_unknown")
+	assert.Equal(t, model.NewEventNotFoundErr("evt_unknown"), err)
+
+	mockEvt.AssertExpectations(t)
+}
+
+func TestReplayEvent_Success(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	stored := &model.Event{
+		EventId:  "evt_123",
+		Type:     model.EventSubscriptionUpdated,
+		Payload:  webhookPayload,
+		Status:   model.EventStatusFailed,
+		Error:    "subscription 'ext_sub_789' not found",
+		Attempts: 3,
+	}
+	parsed := model.Event{
+		EventId: "evt_123",
+		Type:    model.EventSubscriptionUpdated,
+		Payload: webhookPayload,
+		Subscription: &model.EventSubscription{
+			ExternalSubscriptionId: "ext_sub_789",
+			Status:                 "active",
+		},
+	}
+
+	mockEvt.
+		On("GetEvent", ctx, "evt_123").
+		Return(stored, nil).Once()
+
+	mockPay.
+		On("ParseEvent", ctx, webhookPayload).
+		Return(parsed, nil).Once()
+
+	mockSub.
+		On("GetSubscriptionByExternalId", ctx, "ext_sub_789").
+		Return(&model.Subscription{SubscriptionId: "sub_abc", Status: "incomplete"}, nil).Once()
+
+	mockSub.
+		On("UpdateSubscriptionStatus", ctx, mock.Anything, mock.Anything).
+		Return(nil).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusProcessed && e.Attempts == 4 && e.Error == ""
+		})).
+		Return(nil).Once()
+
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	event, err := svc.ReplayEvent(ctx, "evt_123")
+	assert.NoError(t, err)
+	assert.Equal(t, model.EventStatusProcessed, event.Status)
+	assert.Equal(t, 4, event.Attempts)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
+
+func TestReplayEvents_FailureIsReturnedAsOutcome(t *testing.T) {
+	ctx := context.Background()
+
+	mockSub := new(mockSubscription)
+	mockEvt := new(mockEvent)
+	mockPay := new(mockPaymentProvider)
+
+	from := time.Unix(1700000000, 0).UTC()
+	filter := model.EventFilter{Status: model.EventStatusFailed, From: from, To: from.Add(time.Hour), Limit: 1000}
+
+	mockEvt.
+		On("GetEvents", ctx, filter).
+		Return([]model.Event{{EventId: "evt_123", Payload: webhookPayload, Attempts: 1}}, nil).Once()
+
+	mockPay.
+		On("ParseEvent", ctx, webhookPayload).
+		Return(model.Event{
+			EventId: "evt_123",
+			Type:    model.EventInvoicePaid,
+			Invoice: &model.EventInvoice{ExternalSubscriptionId: "ext_sub_789"},
+		}, nil).Once()
+
+	mockPay.
+		On("GetSubscriptionStatus", ctx, "ext_sub_789").
+		Return("", errors.New("payment provider error")).Once()
+
+	mockEvt.
+		On("UpdateEvent", ctx, mock.MatchedBy(func(e model.Event) bool {
+			return e.Status == model.EventStatusFailed && e.Attempts == 2
+		})).
+		Return(nil).Once()
+
+	// A handler failure is part of the replay result, not an error of the request.
+	svc := service.NewWebhookService(mockSub, mockEvt, mockPay, retryConfig)
+	events, err := svc.ReplayEvents(ctx, filter)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, model.EventStatusFailed, events[0].Status)
+	assert.Equal(t, "payment provider error", events[0].Error)
+
+	mockPay.AssertExpectations(t)
+	mockSub.AssertExpectations(t)
+	mockEvt.AssertExpectations(t)
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -107,6 +108,23 @@ func OptionalUrl(key string) *url.URL {
 	return u
 }
 
+// OptionalMap reads comma separated key:value pairs, such as usd:price_1,eur:price_2
+func OptionalMap(key string) map[string]string {
+	v := OptionalString(key)
+	m := map[string]string{}
+	if v == "" {
+		return m
+	}
+	for _, pair := range strings.Split(v, ",") {
+		k, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			panic(fmt.Sprintf("invalid map of env variable '%s': %s", key, v))
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(value)
+	}
+	return m
+}
+
 func RequiredUrl(key string) *url.URL {
 	v := RequiredString(key)
 	u, err := url.Parse(v)
@@ -1 +1,2 @@
-aws dynamodb create-table --cli-input-json file://subscription_dev.json --endpoint-url http://dynamodb:8000
\ No newline at end of file
+aws dynamodb create-table --cli-input-json file://subscription_dev.json --endpoint-url http://dynamodb:8000
+aws dynamodb batch-write-item --request-items file://plans_dev.json --endpoint-url http://dynamodb:8000
\ No newline at end of file